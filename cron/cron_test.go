@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *", "* * 0 * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected error for %q, got nil", expr)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // Friday
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected time.Time
+	}{
+		{"Every minute", "* * * * *", time.Date(2024, time.March, 15, 10, 8, 0, 0, time.UTC)},
+		{"Every 15 minutes", "*/15 * * * *", time.Date(2024, time.March, 15, 10, 15, 0, 0, time.UTC)},
+		{"Daily at 9", "0 9 * * *", time.Date(2024, time.March, 16, 9, 0, 0, 0, time.UTC)},
+		{"Weekdays", "30 8 * * 1-5", time.Date(2024, time.March, 18, 8, 30, 0, 0, time.UTC)},
+		{"Sunday as 7", "0 0 * * 7", time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"First of month", "0 0 1 * *", time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"Day of month or weekday", "0 0 20 * 6", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"List of hours", "0 6,12 * * *", time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		{"Leap day", "0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC).AddDate(4, 0, 0)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if next := schedule.Next(base); !next.Equal(tc.expected) {
+				t.Errorf("Expected next run %v, got %v", tc.expected, next)
+			}
+		})
+	}
+}
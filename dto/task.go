@@ -1,26 +1,200 @@
 package dto
 
-import "scheduler-service/models"
+import (
+	"bytes"
+	"encoding/json"
+	"scheduler-service/models"
+	"time"
+)
 
 type TaskRequest struct {
 	Tasks    string `json:"tasks"`    // Task List
 	Strategy string `json:"strategy"` // Scheduler Strategy: FIFO or SRTF
 }
 
+// TaskSpec describes one submitted task. A bare integer in the submitted
+// array is shorthand for {"duration": n}.
+type TaskSpec struct {
+	Duration  int               `json:"duration"`
+	Group     string            `json:"group,omitempty"`    // scheduling group within the tenant, e.g. "eng/search"
+	Class     string            `json:"class,omitempty"`    // service class, the default class when omitted
+	Estimate  int               `json:"estimate,omitempty"` // declared runtime seen by the strategies, predicted from labels when omitted
+	Labels    map[string]string `json:"labels,omitempty"`
+	Resources models.Resources  `json:"resources,omitempty"`
+	Width     int               `json:"width,omitempty"` // run only with exactly this many bandwidth units
+
+	OnUnderestimate string `json:"on_underestimate,omitempty"` // extend (default) or kill
+
+	Priority int `json:"priority,omitempty"` // higher runs first under every strategy
+	Deadline int `json:"deadline,omitempty"` // tick by which the task should complete, used by EDF
+
+	MinParallelism int                  `json:"min_parallelism,omitempty"`
+	MaxParallelism int                  `json:"max_parallelism,omitempty"`
+	Speedup        *models.SpeedupModel `json:"speedup,omitempty"`
+
+	NotBefore int       `json:"not_before,omitempty"` // earliest tick the task may run
+	StartAt   time.Time `json:"start_at,omitempty"`   // earliest wall-clock time the task may run
+
+	FailureProbability float64             `json:"failure_probability,omitempty"`
+	FailureScript      []bool              `json:"failure_script,omitempty"` // fail the n-th executed cycle
+	Retry              *models.RetryPolicy `json:"retry,omitempty"`
+}
+
+func (s *TaskSpec) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		*s = TaskSpec{}
+		return json.Unmarshal(data, &s.Duration)
+	}
+	type plain TaskSpec
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// TaskUpdateRequest changes a queued task; omitted fields stay unchanged.
+type TaskUpdateRequest struct {
+	Priority *int              `json:"priority,omitempty"`
+	Deadline *int              `json:"deadline,omitempty"` // 0 clears the deadline
+	Labels   map[string]string `json:"labels,omitempty"`   // replaces all labels
+}
+
+// TaskMoveRequest moves a queued task to the front of the queue or ahead of another task.
+type TaskMoveRequest struct {
+	Front  bool `json:"front,omitempty"`
+	Before *int `json:"before,omitempty"` // index of the task to move ahead of
+}
+
 type TaskSubmissionResponse struct {
-	JobID     string `json:"job_id"`
-	Message   string `json:"message"`
-	TaskCount int    `json:"task_count"`
+	JobID       string `json:"job_id"`
+	Message     string `json:"message"`
+	TaskCount   int    `json:"task_count"`
+	ParkedTasks int    `json:"parked_tasks,omitempty"` // tasks waiting for admission within the tenant quota
+}
+
+// GroupRequest creates or changes a scheduling group of the caller's tenant;
+// omitted fields stay unchanged.
+type GroupRequest struct {
+	Path       string `json:"path"` // e.g. "eng/search", "" for the tenant itself
+	Weight     int    `json:"weight,omitempty"`
+	Policy     string `json:"policy,omitempty"`   // fair or priority, between the group's own queue and its subgroups
+	Strategy   string `json:"strategy,omitempty"` // orders the group's own queue
+	Preemptive *bool  `json:"preemptive,omitempty"`
+}
+
+// GroupStatus describes one scheduling group of a tenant.
+type GroupStatus struct {
+	Class       string `json:"class,omitempty"`
+	Path        string `json:"path"`
+	Weight      int    `json:"weight"`
+	Policy      string `json:"policy"`
+	Strategy    string `json:"strategy"`
+	Preemptive  bool   `json:"preemptive"`
+	QueuedTasks int    `json:"queued_tasks"` // in the group's own queue
+}
+
+// UsageQuery selects and groups the recorded usage.
+type UsageQuery struct {
+	From    time.Time // inclusive; zero is the first record
+	To      time.Time // exclusive; zero is now
+	Tenant  string    // empty covers all tenants
+	GroupBy []string  // tenant, job, class or label:<key>
+}
+
+// UsageRow is the usage of one combination of the grouped dimensions.
+type UsageRow struct {
+	Tenant         string            `json:"tenant,omitempty"`
+	Job            string            `json:"job,omitempty"`
+	Class          string            `json:"class,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	BandwidthTicks int               `json:"bandwidth_ticks"`
+	Cost           float64           `json:"cost"`
+}
+
+type UsageResponse struct {
+	From                *time.Time        `json:"from,omitempty"` // rounded down to the minute
+	To                  *time.Time        `json:"to,omitempty"`
+	GroupBy             []string          `json:"group_by"`
+	Prices              models.UnitPrices `json:"prices"`
+	Rows                []UsageRow        `json:"rows"`
+	TotalBandwidthTicks int               `json:"total_bandwidth_ticks"`
+	TotalCost           float64           `json:"total_cost"`
+}
+
+// AuditQuery selects entries of the audit log; zero fields match all.
+type AuditQuery struct {
+	From   time.Time
+	To     time.Time
+	Actor  string
+	Action string
+	Tenant string
+	Limit  int // most recent entries to return, 0 for all
+}
+
+// QuotaResponse reports the quota of a tenant and what counts against it.
+type QuotaResponse struct {
+	Tenant          string       `json:"tenant"`
+	Quota           models.Quota `json:"quota"`
+	QueuedTasks     int          `json:"queued_tasks"`
+	OutstandingWork int          `json:"outstanding_work"`
+	ParkedTasks     int          `json:"parked_tasks"`
+}
+
+// QuotaErrorResponse is the body of a submission rejected by a tenant quota.
+type QuotaErrorResponse struct {
+	Error      string `json:"error"`
+	Reason     string `json:"reason"`
+	Limit      int    `json:"limit"`
+	Requested  int    `json:"requested"`
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds
+}
+
+// StreamErrorResponse is the body of a task stream that stopped part way.
+// The tasks read before the failure stay queued as job JobID.
+type StreamErrorResponse struct {
+	Error      string `json:"error"`
+	JobID      string `json:"job_id"`
+	TaskCount  int    `json:"task_count"`            // tasks queued before the failure
+	Line       int    `json:"line,omitempty"`        // the failing line
+	Reason     string `json:"reason,omitempty"`      // the exceeded quota limit
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds
 }
 
 type StatusResponse struct {
+	Version         uint64                  `json:"version"` // increases with every state change
+	Tenant          string                  `json:"tenant"`
 	CurrentTime     int                     `json:"current_time"`
 	ScheduleHistory []models.ScheduleResult `json:"schedule_history"`
-	ActiveTasks     []models.Task           `json:"active_tasks"`
+	ActiveTasks     []models.Task           `json:"active_tasks"` // the first queued tasks of each group in scheduling order
+	ActiveCount     int                     `json:"active_count"`
+	ActiveTruncated bool                    `json:"active_truncated"` // active_tasks lists fewer than active_count
+	ScheduledTasks  []models.Task           `json:"scheduled_tasks"`
+	PendingTasks    int                     `json:"pending_tasks"` // submitted tasks waiting for the next cycle to be queued
+	ParkedTasks     int                     `json:"parked_tasks"`  // tasks waiting for admission within the tenant quota
 	CompletedTasks  []models.Task           `json:"completed_tasks"`
 	CurrentStrategy string                  `json:"current_strategy"`
+	Preemptive      bool                    `json:"preemptive"`
+	Groups          []GroupStatus           `json:"groups,omitempty"` // the tenant itself and its subgroups
+	BurstCredits    *float64                `json:"burst_credits,omitempty"`
+}
+
+// BandwidthRequest sets the units of every following cycle.
+type BandwidthRequest struct {
+	Bandwidth int `json:"bandwidth"`
 }
 
 type SchedulerSwitchRequest struct {
-	Strategy string `json:"strategy" binding:"required"`
+	Strategy   string `json:"strategy" binding:"required"`
+	Preemptive *bool  `json:"preemptive,omitempty"` // leave the strategy's mode unchanged when omitted
+}
+
+// SchedulerState is a tenant's strategy and its preemption mode.
+type SchedulerState struct {
+	Strategy   string `json:"strategy"`
+	Preemptive bool   `json:"preemptive"`
+}
+
+type RecurringScheduleRequest struct {
+	Cron     string     `json:"cron"`     // five-field cron expression
+	Interval string     `json:"interval"` // or a Go duration such as "30s"
+	Tasks    []TaskSpec `json:"durations"`
+	Strategy string     `json:"strategy"` // runs the tasks in a group of their own under this strategy
+	Overlap  string     `json:"overlap"`  // skip (default) or queue
 }
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"scheduler-service/services"
+	"scheduler-service/utils"
+	"strconv"
+	"time"
+)
+
+// SetAuditLog replaces the in-memory audit log the handler records
+// administrative actions in.
+func (th *TaskHandler) SetAuditLog(audit *services.AuditLog) {
+	th.audit = audit
+}
+
+// GetAudit serves GET /audit: the recorded administrative actions, oldest
+// first, filtered by from, to, actor, action and tenant, and limited to
+// the most recent limit entries.
+func (th *TaskHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	params := r.URL.Query()
+	query := dto.AuditQuery{Actor: params.Get("actor"), Action: params.Get("action"), Tenant: params.Get("tenant")}
+	for name, value := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if params.Get(name) == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, params.Get(name))
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid "+name+" time, expected RFC 3339")
+			return
+		}
+		*value = t
+	}
+	if limit := params.Get("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	entries, err := th.audit.Query(query)
+	if errors.Is(err, services.ErrInvalidAuditQuery) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to read audit log")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, entries)
+}
+
+// record adds an action of r to the audit log.
+func (th *TaskHandler) record(r *http.Request, action, tenant, target string, oldValue, newValue any) {
+	th.audit.Record(models.AuditEntry{
+		Actor:    requestActor(r),
+		SourceIP: sourceIP(r),
+		Action:   action,
+		Tenant:   tenant,
+		Target:   target,
+		OldValue: oldValue,
+		NewValue: newValue,
+	})
+}
+
+// requestActor names who sent r: its API key, else its client certificate.
+func requestActor(r *http.Request) string {
+	if key, ok := r.Context().Value(apiKeyContext{}).(models.APIKey); ok {
+		return "key:" + key.Name
+	}
+	if name, ok := certTenant(r); ok {
+		return "cert:" + name
+	}
+	return "anonymous"
+}
+
+// sourceIP returns the address r came from, without its port.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
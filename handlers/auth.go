@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"scheduler-service/models"
+	"scheduler-service/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of a signed request, see Signature.
+	SignatureHeader = "X-Signature"
+	// TimestampHeader carries the Unix time in seconds a request was signed at.
+	TimestampHeader = "X-Timestamp"
+	// signatureWindow is how far the timestamp of a signed request may be off.
+	signatureWindow = 5 * time.Minute
+	// spoolMemory is how much of a signed body is kept in memory; the rest
+	// waits in a temporary file until the signature is checked.
+	spoolMemory = 1 << 20
+)
+
+// Access is what a route needs from the key of a request.
+type Access int
+
+const (
+	AccessRead    Access = iota // status of the caller's tenant
+	AccessReadAll               // status of all tenants, needs a request not bound to a tenant
+	AccessSubmit                // submitting and changing tasks and schedules
+	AccessAdmin                 // strategies and group policies
+)
+
+var roleAccess = map[models.Role][]Access{
+	models.RoleSubmitter: {AccessRead, AccessSubmit},
+	models.RoleViewer:    {AccessRead, AccessReadAll},
+	models.RoleAdmin:     {AccessRead, AccessReadAll, AccessSubmit, AccessAdmin},
+}
+
+type apiKeyContext struct{}
+
+// Authenticator checks the API key of every request, sent as
+// "Authorization: Bearer <key>", against the role a route needs.
+type Authenticator struct {
+	keys map[[sha256.Size]byte]models.APIKey // 按密钥的哈希查找，不保留明文比较
+	now  func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time // 窗口内用过的签名及其过期时间，拒绝重放
+}
+
+// LoadAPIKeys reads an API key file.
+func LoadAPIKeys(path string) (models.APIKeyConfig, error) {
+	var config models.APIKeyConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid API key file %s: %w", path, err)
+	}
+	return config, config.Validate()
+}
+
+func NewAuthenticator(config models.APIKeyConfig) (*Authenticator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Authenticator{
+		keys: make(map[[sha256.Size]byte]models.APIKey, len(config.Keys)),
+		now:  time.Now,
+		seen: make(map[string]time.Time),
+	}
+	for _, key := range config.Keys {
+		a.keys[sha256.Sum256([]byte(key.Key))] = key
+	}
+	return a, nil
+}
+
+// Require wraps next so that it only serves requests whose key grants
+// access. GET and HEAD requests need no more than AccessRead, except on
+// AccessReadAll routes. A nil Authenticator lets every request through.
+func (a *Authenticator) Require(access Access, next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := a.authenticate(w, r)
+		if !ok {
+			return
+		}
+		defer r.Body.Close()
+
+		needed := access
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && access != AccessReadAll {
+			needed = AccessRead
+		}
+		if cert, ok := certTenant(r); ok && key.Tenant != "" && key.Tenant != cert {
+			utils.WriteErrorResponse(w, http.StatusForbidden,
+				fmt.Sprintf("API key %s is bound to tenant %s, the client certificate to %s", key.Name, key.Tenant, cert))
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), apiKeyContext{}, key))
+		_, bound := boundTenant(r)
+		if !slices.Contains(roleAccess[key.Role], needed) || (needed == AccessReadAll && bound) {
+			utils.WriteErrorResponse(w, http.StatusForbidden,
+				fmt.Sprintf("API key %s with role %s may not %s %s", key.Name, key.Role, r.Method, r.URL.Path))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authenticate returns the key of r, or writes a 401 response and returns
+// false if r carries no valid key or, for a key with a secret, no valid
+// signature or one that was already used.
+func (a *Authenticator) authenticate(w http.ResponseWriter, r *http.Request) (models.APIKey, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	key, exists := a.keys[sha256.Sum256([]byte(token))]
+	if !found || !exists {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Missing or invalid API key")
+		return key, false
+	}
+	if key.HMACSecret == "" {
+		return key, true
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || a.now().Sub(time.Unix(seconds, 0)).Abs() > signatureWindow {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Missing or expired "+TimestampHeader+" header")
+		return key, false
+	}
+	// 签名覆盖请求体，校验通过前不交给处理函数；读出时边读边计算，
+	// 大的请求体暂存到临时文件，不占用内存
+	mac := signatureMAC(key.HMACSecret, r.Method, r.URL.RequestURI(), timestamp)
+	body, err := spoolBody(r.Body, mac, spoolMemory)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+		return key, false
+	}
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(SignatureHeader))) {
+		body.Close()
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid "+SignatureHeader+" header")
+		return key, false
+	}
+	if !a.firstUse(key.Name+"\n"+expected, time.Unix(seconds, 0).Add(signatureWindow)) {
+		body.Close()
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Replayed "+SignatureHeader+" header")
+		return key, false
+	}
+	r.Body = body
+	return key, true
+}
+
+// spoolBody reads body to the end, writing it to mac on the way, and
+// returns a reader that replays it. Up to limit bytes are kept in memory,
+// the rest in a temporary file that closing the reader removes.
+func spoolBody(body io.Reader, mac io.Writer, limit int64) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(io.MultiWriter(&buf, mac), body, limit); err == io.EOF {
+		return io.NopCloser(&buf), nil
+	} else if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp("", "signed-body-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := spoolFile{file}
+	if _, err := io.Copy(file, &buf); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(io.MultiWriter(file, mac), body); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// spoolFile is a temporary file that is removed when closed.
+type spoolFile struct {
+	*os.File
+}
+
+func (f spoolFile) Close() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// firstUse records a signature until it expires and reports whether it was
+// not recorded yet. Expired signatures are dropped, since their timestamp
+// no longer passes the window.
+func (a *Authenticator) firstUse(signature string, expires time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for seen, expiry := range a.seen {
+		if now.After(expiry) {
+			delete(a.seen, seen)
+		}
+	}
+	if _, used := a.seen[signature]; used {
+		return false
+	}
+	a.seen[signature] = expires
+	return true
+}
+
+// Signature returns the hex HMAC-SHA256, keyed by secret, of the method,
+// the path with its query, the timestamp and the body of a request, each
+// followed by a newline except the body.
+func Signature(secret, method, requestURI, timestamp string, body []byte) string {
+	mac := signatureMAC(secret, method, requestURI, timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureMAC returns the HMAC of Signature with everything but the body written.
+func signatureMAC(secret, method, requestURI, timestamp string) hash.Hash {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", method, requestURI, timestamp)
+	return mac
+}
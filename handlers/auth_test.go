@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"scheduler-service/models"
+	"scheduler-service/services"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAuthenticator_Require(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	auth, err := NewAuthenticator(models.APIKeyConfig{Keys: []models.APIKey{
+		{Name: "ci", Key: "submitter-key-0001", Role: models.RoleSubmitter, Tenant: "team-a"},
+		{Name: "dashboard", Key: "viewer-key-000001", Role: models.RoleViewer},
+		{Name: "ops", Key: "admin-key-0000001", Role: models.RoleAdmin},
+		{Name: "signed", Key: "signed-key-000001", Role: models.RoleSubmitter, Tenant: "team-s", HMACSecret: "secret"},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	auth.now = func() time.Time { return now }
+	taskHandler := NewTaskHandler(services.NewTaskService(2))
+
+	routes := map[string]http.HandlerFunc{
+		"/tasks":        auth.Require(AccessSubmit, taskHandler.SubmitTasks),
+		"/status":       auth.Require(AccessRead, taskHandler.GetStatus),
+		"/metrics":      auth.Require(AccessReadAll, taskHandler.GetMetrics),
+		"/scheduler":    auth.Require(AccessAdmin, taskHandler.SwitchScheduler),
+		"/bandwidth":    auth.Require(AccessAdmin, taskHandler.Bandwidth),
+		"/quota":        auth.Require(AccessAdmin, taskHandler.Quota),
+		"/tasks/stream": auth.Require(AccessSubmit, taskHandler.StreamTasks),
+	}
+	fresh := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		key            string
+		tenant         string
+		body           string
+		timestamp      string
+		signature      string // "" signs the request correctly
+		expectedStatus int
+	}{
+		{"No key", http.MethodGet, "/status", "", "", "", "", "", http.StatusUnauthorized},
+		{"Unknown key", http.MethodGet, "/status", "unknown-key-00001", "", "", "", "", http.StatusUnauthorized},
+		{"Submitter submits", http.MethodPost, "/tasks", "submitter-key-0001", "", `[3]`, "", "", http.StatusOK},
+		{"Submitter for other tenant", http.MethodPost, "/tasks", "submitter-key-0001", "team-b", `[3]`, "", "", http.StatusForbidden},
+		{"Submitter reads own status", http.MethodGet, "/status", "submitter-key-0001", "", "", "", "", http.StatusOK},
+		{"Submitter reads metrics", http.MethodGet, "/metrics", "submitter-key-0001", "", "", "", "", http.StatusForbidden},
+		{"Submitter switches strategy", http.MethodPost, "/scheduler", "submitter-key-0001", "", `{"strategy": "SRTF"}`, "", "", http.StatusForbidden},
+		{"Viewer reads metrics", http.MethodGet, "/metrics", "viewer-key-000001", "", "", "", "", http.StatusOK},
+		{"Viewer submits", http.MethodPost, "/tasks", "viewer-key-000001", "", `[3]`, "", "", http.StatusForbidden},
+		{"Admin switches strategy", http.MethodPost, "/scheduler", "admin-key-0000001", "team-b", `{"strategy": "SRTF"}`, "", "", http.StatusOK},
+		{"Submitter sets bandwidth", http.MethodPut, "/bandwidth", "submitter-key-0001", "", `{"bandwidth": 4}`, "", "", http.StatusForbidden},
+		{"Viewer reads bandwidth", http.MethodGet, "/bandwidth", "viewer-key-000001", "", "", "", "", http.StatusOK},
+		{"Submitter sets own quota", http.MethodPut, "/quota", "submitter-key-0001", "", `{"max_queued_tasks": 100}`, "", "", http.StatusForbidden},
+		{"Submitter reads own quota", http.MethodGet, "/quota", "submitter-key-0001", "", "", "", "", http.StatusOK},
+		{"Admin sets bandwidth", http.MethodPut, "/bandwidth", "admin-key-0000001", "", `{"bandwidth": 4}`, "", "", http.StatusOK},
+		{"Signed", http.MethodPost, "/tasks", "signed-key-000001", "", `[3]`, fresh, "", http.StatusOK},
+		{"Replayed signature", http.MethodPost, "/tasks", "signed-key-000001", "", `[3]`, fresh, "", http.StatusUnauthorized},
+		{"Signed again", http.MethodPost, "/tasks", "signed-key-000001", "", `[3]`, strconv.FormatInt(now.Unix()+1, 10), "", http.StatusOK},
+		{"Unsigned", http.MethodPost, "/tasks", "signed-key-000001", "", `[3]`, "", "", http.StatusUnauthorized},
+		{"Stale signature", http.MethodPost, "/tasks", "signed-key-000001", "", `[3]`, stale, "", http.StatusUnauthorized},
+		{"Wrong signature", http.MethodPost, "/tasks", "signed-key-000001", "", `[3]`, fresh, "00", http.StatusUnauthorized},
+		{"Signed stream", http.MethodPost, "/tasks/stream", "signed-key-000001", "", "3\n4\n", fresh, "", http.StatusOK},
+		{"Wrong stream signature", http.MethodPost, "/tasks/stream", "signed-key-000001", "", "3\n4\n", fresh, "00", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			if tc.tenant != "" {
+				req.Header.Set(TenantHeader, tc.tenant)
+			}
+			if tc.timestamp != "" {
+				signature := tc.signature
+				if signature == "" {
+					signature = Signature("secret", tc.method, tc.path, tc.timestamp, []byte(tc.body))
+				}
+				req.Header.Set(TimestampHeader, tc.timestamp)
+				req.Header.Set(SignatureHeader, signature)
+			}
+			resp := httptest.NewRecorder()
+
+			routes[tc.path](resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+		})
+	}
+}
+
+func TestRequestTenant_ClientCertificate(t *testing.T) {
+	auth, err := NewAuthenticator(models.APIKeyConfig{Keys: []models.APIKey{
+		{Name: "ci", Key: "submitter-key-0001", Role: models.RoleSubmitter, Tenant: "team-a"},
+		{Name: "dashboard", Key: "viewer-key-000001", Role: models.RoleViewer},
+		{Name: "ops", Key: "admin-key-0000001", Role: models.RoleAdmin},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taskHandler := NewTaskHandler(services.NewTaskService(2))
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		path           string
+		key            string
+		tenant         string
+		commonName     string
+		expectedStatus int
+		expectedTenant string // tenant of the quota served
+	}{
+		{"Certificate without key", taskHandler.Quota, "/quota", "", "", "team-c", http.StatusOK, "team-c"},
+		{"Certificate and header differ", taskHandler.Quota, "/quota", "", "team-d", "team-c", http.StatusForbidden, ""},
+		{"Certificate and key differ", auth.Require(AccessRead, taskHandler.Quota), "/quota", "submitter-key-0001", "", "team-c", http.StatusForbidden, ""},
+		{"Certificate with viewer key reads metrics", auth.Require(AccessReadAll, taskHandler.GetMetrics), "/metrics", "viewer-key-000001", "", "team-c", http.StatusOK, ""},
+		{"Viewer key without certificate reads metrics", auth.Require(AccessReadAll, taskHandler.GetMetrics), "/metrics", "viewer-key-000001", "", "", http.StatusOK, ""},
+		{"Certificate with admin key reads audit", auth.Require(AccessReadAll, taskHandler.GetAudit), "/audit", "admin-key-0000001", "", "team-c", http.StatusOK, ""},
+		{"Certificate with admin key acts for header", auth.Require(AccessRead, taskHandler.Quota), "/quota", "admin-key-0000001", "team-d", "team-c", http.StatusOK, "team-d"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			if tc.tenant != "" {
+				req.Header.Set(TenantHeader, tc.tenant)
+			}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: tc.commonName}}}}}
+			resp := httptest.NewRecorder()
+
+			tc.handler(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+			if tc.expectedTenant != "" && !bytes.Contains(resp.Body.Bytes(), []byte(`"tenant":"`+tc.expectedTenant+`"`)) {
+				t.Errorf("Expected the quota of %s, got %s", tc.expectedTenant, resp.Body)
+			}
+		})
+	}
+}
+
+func TestSpoolBody(t *testing.T) {
+	for _, size := range []int{0, 10, 16, 100} {
+		body := bytes.Repeat([]byte("x"), size)
+		mac := sha256.New()
+		spooled, err := spoolBody(bytes.NewReader(body), mac, 16)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		replayed, err := io.ReadAll(spooled)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !bytes.Equal(replayed, body) {
+			t.Errorf("Expected %d bytes replayed, got %d", size, len(replayed))
+		}
+		if sum := sha256.Sum256(body); !bytes.Equal(mac.Sum(nil), sum[:]) {
+			t.Errorf("Expected the whole body of %d bytes to be hashed", size)
+		}
+
+		// 超出内存上限的部分暂存在临时文件中，关闭后删除
+		file, onDisk := spooled.(spoolFile)
+		if onDisk != (size >= 16) {
+			t.Errorf("Expected a body of %d bytes on disk: %v", size, size >= 16)
+		}
+		spooled.Close()
+		if onDisk {
+			if _, err := os.Stat(file.Name()); !os.IsNotExist(err) {
+				t.Errorf("Expected %s to be removed, got %v", file.Name(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"scheduler-service/dto"
+	"scheduler-service/services"
+	"scheduler-service/utils"
+)
+
+type ScheduleHandler struct {
+	recurringService *services.RecurringService
+}
+
+func NewScheduleHandler(recurringService *services.RecurringService) *ScheduleHandler {
+	return &ScheduleHandler{
+		recurringService: recurringService,
+	}
+}
+
+// Schedules serves GET and POST /schedules.
+func (sh *ScheduleHandler) Schedules(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		utils.WriteJSONResponse(w, http.StatusOK, sh.recurringService.List(tenant))
+	case http.MethodPost:
+		var req dto.RecurringScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+		schedule, err := sh.recurringService.Create(tenant, req)
+		if err != nil {
+			writeScheduleError(w, err)
+			return
+		}
+		utils.WriteJSONResponse(w, http.StatusCreated, schedule)
+	default:
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET and POST methods are allowed")
+	}
+}
+
+// Schedule serves GET and DELETE /schedules/{id}.
+func (sh *ScheduleHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		schedule, err := sh.recurringService.Get(tenant, id)
+		if err != nil {
+			writeScheduleError(w, err)
+			return
+		}
+		utils.WriteJSONResponse(w, http.StatusOK, schedule)
+	case http.MethodDelete:
+		if err := sh.recurringService.Delete(tenant, id); err != nil {
+			writeScheduleError(w, err)
+			return
+		}
+		utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"message": "Schedule deleted",
+			"id":      id,
+		})
+	default:
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET and DELETE methods are allowed")
+	}
+}
+
+// PauseSchedule serves POST /schedules/{id}/pause.
+func (sh *ScheduleHandler) PauseSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := sh.recurringService.Pause(tenant, r.PathValue("id"))
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, schedule)
+}
+
+// ResumeSchedule serves POST /schedules/{id}/resume.
+func (sh *ScheduleHandler) ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := sh.recurringService.Resume(tenant, r.PathValue("id"))
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, schedule)
+}
+
+func writeScheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrScheduleNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidSchedule):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update schedule")
+	}
+}
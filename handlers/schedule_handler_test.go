@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"scheduler-service/models"
+	"scheduler-service/services"
+	"testing"
+)
+
+func TestScheduleHandler_Schedules(t *testing.T) {
+	scheduleHandler := NewScheduleHandler(services.NewRecurringService(services.NewTaskService(5)))
+
+	tests := []struct {
+		name           string
+		method         string
+		body           []byte
+		expectedStatus int
+	}{
+		{"Create with interval", http.MethodPost, []byte(`{"interval": "30s", "durations": [3, 5]}`), http.StatusCreated},
+		{"Create with cron", http.MethodPost, []byte(`{"cron": "0 * * * *", "durations": [3], "overlap": "queue"}`), http.StatusCreated},
+		{"Invalid JSON", http.MethodPost, []byte(`{invalid json}`), http.StatusBadRequest},
+		{"Invalid cron", http.MethodPost, []byte(`{"cron": "bad", "durations": [3]}`), http.StatusBadRequest},
+		{"List", http.MethodGet, nil, http.StatusOK},
+		{"Invalid method", http.MethodPut, nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/schedules", bytes.NewBuffer(tc.body))
+			resp := httptest.NewRecorder()
+
+			scheduleHandler.Schedules(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.Code)
+			}
+		})
+	}
+}
+
+func TestScheduleHandler_Lifecycle(t *testing.T) {
+	scheduleHandler := NewScheduleHandler(services.NewRecurringService(services.NewTaskService(5)))
+
+	req := httptest.NewRequest(http.MethodPost, "/schedules", bytes.NewBufferString(`{"interval": "1m", "durations": [2]}`))
+	resp := httptest.NewRecorder()
+	scheduleHandler.Schedules(resp, req)
+
+	var created models.RecurringSchedule
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		id             string
+		handler        http.HandlerFunc
+		expectedStatus int
+	}{
+		{"Get", http.MethodGet, created.ID, scheduleHandler.Schedule, http.StatusOK},
+		{"Get unknown", http.MethodGet, "missing", scheduleHandler.Schedule, http.StatusNotFound},
+		{"Pause", http.MethodPost, created.ID, scheduleHandler.PauseSchedule, http.StatusOK},
+		{"Pause with GET", http.MethodGet, created.ID, scheduleHandler.PauseSchedule, http.StatusMethodNotAllowed},
+		{"Resume", http.MethodPost, created.ID, scheduleHandler.ResumeSchedule, http.StatusOK},
+		{"Resume unknown", http.MethodPost, "missing", scheduleHandler.ResumeSchedule, http.StatusNotFound},
+		{"Delete", http.MethodDelete, created.ID, scheduleHandler.Schedule, http.StatusOK},
+		{"Delete again", http.MethodDelete, created.ID, scheduleHandler.Schedule, http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/schedules/"+tc.id, nil)
+			req.SetPathValue("id", tc.id)
+			resp := httptest.NewRecorder()
+
+			tc.handler(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.Code)
+			}
+		})
+	}
+}
@@ -2,20 +2,27 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"reflect"
 	"scheduler-service/dto"
+	"scheduler-service/models"
 	"scheduler-service/services"
 	"scheduler-service/utils"
+	"strconv"
 )
 
 type TaskHandler struct {
 	taskService *services.TaskService
+	audit       *services.AuditLog
 }
 
 func NewTaskHandler(taskService *services.TaskService) *TaskHandler {
 	return &TaskHandler{
 		taskService: taskService,
+		audit:       services.NewAuditLog(),
 	}
 }
 
@@ -25,32 +32,277 @@ func (th *TaskHandler) SubmitTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var timeSlices []int
-	if err := json.NewDecoder(r.Body).Decode(&timeSlices); err != nil {
-		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format, expected integer array")
+	tenant, ok := requestTenant(w, r)
+	if !ok {
 		return
 	}
 
-	if len(timeSlices) == 0 {
+	var specs []dto.TaskSpec
+	if err := json.NewDecoder(r.Body).Decode(&specs); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format, expected array of durations or task objects")
+		return
+	}
+
+	if len(specs) == 0 {
 		utils.WriteErrorResponse(w, http.StatusBadRequest, "Task list cannot be empty")
 		return
 	}
 
-	response, err := th.taskService.SubmitTasks(timeSlices)
+	response, err := th.taskService.SubmitTaskSpecs(tenant, specs)
 	if err != nil {
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to submit tasks")
+		writeSubmitError(w, err)
+		return
+	}
+	writeSubmission(w, response)
+}
+
+// StreamTasks serves POST /tasks/stream with one task per line (JSON Lines),
+// for submissions too large to send as a single array.
+func (th *TaskHandler) StreamTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := th.taskService.SubmitTaskStream(tenant, r.Body)
+	var streamErr *services.StreamError
+	if errors.As(err, &streamErr) {
+		writeStreamError(w, streamErr)
+		return
+	}
+	if err != nil {
+		writeSubmitError(w, err)
+		return
+	}
+	if response.TaskCount == 0 {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Task list cannot be empty")
+		return
+	}
+	writeSubmission(w, response)
+}
+
+// writeSubmission answers 202 Accepted when some tasks were parked by the tenant quota.
+func writeSubmission(w http.ResponseWriter, response *dto.TaskSubmissionResponse) {
+	if response.ParkedTasks > 0 {
+		utils.WriteJSONResponse(w, http.StatusAccepted, response)
 		return
 	}
 	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
+// writeSubmitError answers a submission over the tenant quota with 429 and
+// Retry-After, or with 400 when waiting cannot help.
+func writeSubmitError(w http.ResponseWriter, err error) {
+	var quotaErr *services.QuotaError
+	switch {
+	case errors.As(err, &quotaErr):
+		body := dto.QuotaErrorResponse{
+			Error:     err.Error(),
+			Reason:    quotaErr.Reason,
+			Limit:     quotaErr.Limit,
+			Requested: quotaErr.Requested,
+		}
+		if quotaErr.RetryAfter <= 0 {
+			utils.WriteJSONResponse(w, http.StatusBadRequest, body)
+			return
+		}
+		body.RetryAfter = retryAfter(w, quotaErr)
+		utils.WriteJSONResponse(w, http.StatusTooManyRequests, body)
+	case errors.Is(err, services.ErrInvalidTask):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to submit tasks")
+	}
+}
+
+// writeStreamError answers a task stream that stopped part way with the
+// job and count of the tasks queued before it, and the failing line: 429
+// with Retry-After when the quota will take the rest later, 400 otherwise.
+func writeStreamError(w http.ResponseWriter, err *services.StreamError) {
+	body := dto.StreamErrorResponse{
+		Error:     err.Error(),
+		JobID:     err.JobID,
+		TaskCount: err.TaskCount,
+		Line:      err.Line,
+	}
+	var quotaErr *services.QuotaError
+	if errors.As(err, &quotaErr) {
+		body.Reason = quotaErr.Reason
+		if quotaErr.RetryAfter > 0 {
+			body.RetryAfter = retryAfter(w, quotaErr)
+			utils.WriteJSONResponse(w, http.StatusTooManyRequests, body)
+			return
+		}
+	}
+	utils.WriteJSONResponse(w, http.StatusBadRequest, body)
+}
+
+// retryAfter sets the Retry-After header for quotaErr and returns its seconds.
+func retryAfter(w http.ResponseWriter, quotaErr *services.QuotaError) int {
+	seconds := int(math.Ceil(quotaErr.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
+
+// Quota serves GET /quota with the caller's tenant quota and usage, and PUT
+// /quota to replace the quota of the caller's tenant.
+func (th *TaskHandler) Quota(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var quota models.Quota
+		if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+		previous, err := th.taskService.SetQuota(tenant, quota)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		th.record(r, models.AuditSetQuotas, tenant, "", previous, quota)
+	default:
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET and PUT methods are allowed")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, th.taskService.GetQuota(tenant))
+}
+
+// Task serves PATCH and DELETE /tasks/{index}.
+func (th *TaskHandler) Task(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid task index")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var req dto.TaskUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+		previous, task, err := th.taskService.UpdateTask(tenant, index, req)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		th.record(r, models.AuditUpdateTask, tenant, strconv.Itoa(index), editableFields(previous), editableFields(task))
+		utils.WriteJSONResponse(w, http.StatusOK, task)
+	case http.MethodDelete:
+		task, err := th.taskService.CancelTask(tenant, index)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		th.record(r, models.AuditCancelTask, tenant, strconv.Itoa(index), task, nil)
+		utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"message": "Task cancelled",
+			"task":    task,
+		})
+	default:
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only PATCH and DELETE methods are allowed")
+	}
+}
+
+// MoveTask serves POST /tasks/{index}/move.
+func (th *TaskHandler) MoveTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid task index")
+		return
+	}
+
+	var req dto.TaskMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	previous, task, err := th.taskService.MoveTask(tenant, index, req)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	th.record(r, models.AuditMoveTask, tenant, strconv.Itoa(index),
+		map[string]int{"priority": previous.Priority, "rank": previous.Rank},
+		map[string]int{"priority": task.Priority, "rank": task.Rank})
+	utils.WriteJSONResponse(w, http.StatusOK, task)
+}
+
+// editableFields returns what PATCH /tasks/{index} can change of task.
+func editableFields(task *models.Task) dto.TaskUpdateRequest {
+	return dto.TaskUpdateRequest{Priority: &task.Priority, Deadline: &task.Deadline, Labels: task.Labels}
+}
+
+func writeTaskError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidTask):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update task")
+	}
+}
+
+func (th *TaskHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, th.taskService.GetMetrics())
+}
+
+func (th *TaskHandler) GetDeadTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, th.taskService.GetDeadTasks(tenant))
+}
+
 func (th *TaskHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET method is allowed")
 		return
 	}
 
-	status := th.taskService.GetStatus()
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+	status := th.taskService.GetStatus(tenant)
 	utils.WriteJSONResponse(w, http.StatusOK, status)
 }
 
@@ -60,6 +312,11 @@ func (th *TaskHandler) SwitchScheduler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
 	var req dto.SchedulerSwitchRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
@@ -86,14 +343,91 @@ func (th *TaskHandler) SwitchScheduler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := th.taskService.SwitchScheduler(req.Strategy); err != nil {
+	// 出错前可能已改了抢占模式，有变化就要审计
+	previous, current, err := th.taskService.UpdateScheduler(tenant, req)
+	if err == nil || previous != current {
+		th.record(r, models.AuditSwitchScheduler, tenant, "", previous, current)
+	}
+	if errors.Is(err, services.ErrPartitioned) {
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to switch scheduler strategy")
 		return
 	}
 
 	response := map[string]interface{}{
 		"message":          fmt.Sprintf("Scheduler strategy switched to: %s", req.Strategy),
-		"current_strategy": req.Strategy,
+		"current_strategy": current.Strategy,
+		"preemptive":       current.Preemptive,
 	}
 	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
+
+// Bandwidth serves GET /bandwidth with the units of one cycle, and PUT
+// /bandwidth to change them from the next cycle on.
+func (th *TaskHandler) Bandwidth(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req dto.BandwidthRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+		previous, err := th.taskService.SetBandwidth(req.Bandwidth)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		th.record(r, models.AuditSetBandwidth, "", "", previous, req.Bandwidth)
+	default:
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET and PUT methods are allowed")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, dto.BandwidthRequest{Bandwidth: th.taskService.GetBandwidth()})
+}
+
+// Groups serves GET /groups with the scheduling groups of the caller's
+// tenant, and PUT /groups to create or change one.
+func (th *TaskHandler) Groups(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := requestTenant(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req dto.GroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+			return
+		}
+		previous, current, err := th.taskService.SetGroup(tenant, req)
+		if err == nil || !reflect.DeepEqual(previous, current) {
+			th.record(r, models.AuditSetGroup, tenant, req.Path, auditGroup(previous), auditGroup(current))
+		}
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET and PUT methods are allowed")
+		return
+	}
+
+	groups := th.taskService.GetStatus(tenant).Groups
+	if groups == nil {
+		groups = make([]dto.GroupStatus, 0)
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, groups)
+}
+
+// auditGroup returns group for the audit log, nil if there is none.
+func auditGroup(group *dto.GroupStatus) any {
+	if group == nil {
+		return nil
+	}
+	return *group
+}
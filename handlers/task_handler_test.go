@@ -6,13 +6,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"scheduler-service/dto"
+	"scheduler-service/models"
 
 	"scheduler-service/services"
+	"strconv"
 	"testing"
 )
 
 func TestTaskHandler_SubmitTasks(t *testing.T) {
 	taskService := services.NewTaskService(5)
+	taskService.SetCapacity(models.Resources{"cpu": 4})
 	taskHandler := NewTaskHandler(taskService)
 
 	tests := []struct {
@@ -43,6 +46,35 @@ func TestTaskHandler_SubmitTasks(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:           "Task objects",
+			method:         http.MethodPost,
+			body:           []byte(`[4, {"duration": 3, "resources": {"cpu": 2}}]`),
+			expectedStatus: http.StatusOK,
+			validateResp: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				var response dto.TaskSubmissionResponse
+				if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if response.TaskCount != 2 {
+					t.Errorf("Expected task count to be 2, got %d", response.TaskCount)
+				}
+			},
+		},
+		{
+			name:           "Resources over capacity",
+			method:         http.MethodPost,
+			body:           []byte(`[{"duration": 3, "resources": {"cpu": 8}}]`),
+			expectedStatus: http.StatusBadRequest,
+			validateResp:   nil,
+		},
+		{
+			name:           "Negative resources",
+			method:         http.MethodPost,
+			body:           []byte(`[{"duration": 3, "resources": {"cpu": -100}}]`),
+			expectedStatus: http.StatusBadRequest,
+			validateResp:   nil,
+		},
 		{
 			name:           "Invalid method",
 			method:         http.MethodGet,
@@ -156,6 +188,12 @@ func TestTaskHandler_SwitchScheduler(t *testing.T) {
 			body:           []byte(`{"strategy":"FIFO"}`),
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "Valid request - non-preemptive",
+			method:         http.MethodPost,
+			body:           []byte(`{"strategy":"SRTF","preemptive":false}`),
+			expectedStatus: http.StatusOK,
+		},
 		{
 			name:           "Invalid method",
 			method:         http.MethodGet,
@@ -220,3 +258,500 @@ func TestTaskHandler_SwitchScheduler(t *testing.T) {
 		})
 	}
 }
+
+func TestTaskHandler_GetDeadTasks(t *testing.T) {
+	taskService := services.NewTaskService(5)
+	taskHandler := NewTaskHandler(taskService)
+
+	if _, err := taskService.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 2, FailureProbability: 1}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taskService.ExecuteSchedulingCycle()
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/dead", nil)
+	resp := httptest.NewRecorder()
+	taskHandler.GetDeadTasks(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.Code)
+	}
+	var dead []models.Task
+	if err := json.NewDecoder(resp.Body).Decode(&dead); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(dead) != 1 || !dead[0].IsFailed {
+		t.Errorf("Expected 1 failed task, got %v", dead)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tasks/dead", nil)
+	resp = httptest.NewRecorder()
+	taskHandler.GetDeadTasks(resp, req)
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, resp.Code)
+	}
+}
+
+func TestTaskHandler_GetMetrics(t *testing.T) {
+	taskHandler := NewTaskHandler(services.NewTaskService(5))
+
+	tests := []struct {
+		name           string
+		method         string
+		expectedStatus int
+	}{
+		{"Valid request", http.MethodGet, http.StatusOK},
+		{"Invalid method", http.MethodPost, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/metrics", nil)
+			resp := httptest.NewRecorder()
+
+			taskHandler.GetMetrics(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.Code)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Task(t *testing.T) {
+	taskService := services.NewTaskService(5)
+	taskHandler := NewTaskHandler(taskService)
+
+	if _, err := taskService.SubmitTasks(models.DefaultTenant, []int{3, 3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	index := strconv.Itoa(taskService.GetStatus(models.DefaultTenant).ActiveTasks[1].Index)
+
+	tests := []struct {
+		name           string
+		method         string
+		index          string
+		body           string
+		handler        http.HandlerFunc
+		expectedStatus int
+	}{
+		{"Update priority", http.MethodPatch, index, `{"priority": 3}`, taskHandler.Task, http.StatusOK},
+		{"Update unknown task", http.MethodPatch, "999999", `{"priority": 3}`, taskHandler.Task, http.StatusNotFound},
+		{"Update with invalid index", http.MethodPatch, "abc", `{}`, taskHandler.Task, http.StatusBadRequest},
+		{"Update with negative deadline", http.MethodPatch, index, `{"deadline": -1}`, taskHandler.Task, http.StatusBadRequest},
+		{"Update with POST", http.MethodPost, index, `{}`, taskHandler.Task, http.StatusMethodNotAllowed},
+		{"Move to front", http.MethodPost, index, `{"front": true}`, taskHandler.MoveTask, http.StatusOK},
+		{"Move without position", http.MethodPost, index, `{}`, taskHandler.MoveTask, http.StatusBadRequest},
+		{"Move before unknown task", http.MethodPost, index, `{"before": 999999}`, taskHandler.MoveTask, http.StatusNotFound},
+		{"Cancel", http.MethodDelete, index, ``, taskHandler.Task, http.StatusOK},
+		{"Cancel again", http.MethodDelete, index, ``, taskHandler.Task, http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/tasks/"+tc.index, bytes.NewBufferString(tc.body))
+			req.SetPathValue("index", tc.index)
+			resp := httptest.NewRecorder()
+
+			tc.handler(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.Code)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_StreamTasks(t *testing.T) {
+	taskHandler := NewTaskHandler(services.NewTaskService(5))
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedLine   int // failing line reported with the queued tasks
+	}{
+		{"Valid stream", http.MethodPost, "3\n{\"duration\": 2}\n", http.StatusOK, 0},
+		{"Empty stream", http.MethodPost, "\n", http.StatusBadRequest, 0},
+		{"Invalid line", http.MethodPost, "3\n\nabc\n", http.StatusBadRequest, 3},
+		{"Stream with GET", http.MethodGet, "", http.StatusMethodNotAllowed, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/tasks/stream", bytes.NewBufferString(tc.body))
+			resp := httptest.NewRecorder()
+
+			taskHandler.StreamTasks(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.Code)
+			}
+			if tc.expectedLine > 0 {
+				var body dto.StreamErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if body.Line != tc.expectedLine || body.TaskCount != 1 || body.JobID == "" {
+					t.Errorf("Expected line %d after 1 queued task of a job, got %+v", tc.expectedLine, body)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Tenants(t *testing.T) {
+	taskService := services.NewTaskService(5)
+	taskHandler := NewTaskHandler(taskService)
+	if _, err := taskService.SubmitTasks("team-a", []int{3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		tenant         string
+		expectedStatus int
+		expectedTasks  int
+	}{
+		{"Own tasks", "team-a", http.StatusOK, 1},
+		{"Other tenant", "team-b", http.StatusOK, 0},
+		{"Default tenant", "", http.StatusOK, 0},
+		{"Invalid tenant", "team a/../", http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			req.Header.Set(TenantHeader, tc.tenant)
+			resp := httptest.NewRecorder()
+
+			taskHandler.GetStatus(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, resp.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var status dto.StatusResponse
+			if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(status.ActiveTasks) != tc.expectedTasks {
+				t.Errorf("Expected %d active tasks, got %d", tc.expectedTasks, len(status.ActiveTasks))
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Quota(t *testing.T) {
+	taskService := services.NewTaskService(2)
+	err := taskService.SetQuotas(models.QuotaConfig{
+		Default: models.Quota{MaxQueuedTasks: 1, MaxTaskDuration: 10},
+		Tenants: map[string]models.Quota{"team-p": {MaxQueuedTasks: 1, OnExceed: models.QuotaPark}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taskHandler := NewTaskHandler(taskService)
+
+	tests := []struct {
+		name           string
+		tenant         string
+		body           string
+		expectedStatus int
+		retryAfter     string
+	}{
+		{"Within quota", "team-a", `[3]`, http.StatusOK, ""},
+		{"Over quota", "team-a", `[3]`, http.StatusTooManyRequests, "2"},
+		{"Task too long", "team-a", `[11]`, http.StatusBadRequest, ""},
+		{"Parked", "team-p", `[3, 3]`, http.StatusAccepted, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(tc.body))
+			req.Header.Set(TenantHeader, tc.tenant)
+			resp := httptest.NewRecorder()
+
+			taskHandler.SubmitTasks(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+			if got := resp.Header().Get("Retry-After"); got != tc.retryAfter {
+				t.Errorf("Expected Retry-After %q, got %q", tc.retryAfter, got)
+			}
+			if tc.expectedStatus == http.StatusTooManyRequests {
+				var body dto.QuotaErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if body.Reason != services.ReasonQueuedTasks || body.Limit != 1 || body.Requested != 2 {
+					t.Errorf("Unexpected quota error: %+v", body)
+				}
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/quota", nil)
+	req.Header.Set(TenantHeader, "team-p")
+	resp := httptest.NewRecorder()
+	taskHandler.Quota(resp, req)
+
+	var quota dto.QuotaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&quota); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if quota.QueuedTasks != 1 || quota.ParkedTasks != 1 {
+		t.Errorf("Expected 1 queued and 1 parked task, got %+v", quota)
+	}
+}
+
+func TestTaskHandler_Groups(t *testing.T) {
+	taskHandler := NewTaskHandler(services.NewTaskService(5))
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedGroups int
+	}{
+		{"No groups yet", http.MethodGet, "", http.StatusOK, 0},
+		{"Create group", http.MethodPut, `{"path": "eng/search", "weight": 2, "strategy": "SRTF"}`, http.StatusOK, 3},
+		{"Invalid path", http.MethodPut, `{"path": "/eng"}`, http.StatusBadRequest, 0},
+		{"Invalid policy", http.MethodPut, `{"path": "eng", "policy": "random"}`, http.StatusBadRequest, 0},
+		{"Unknown strategy", http.MethodPut, `{"path": "eng", "strategy": "LIFO"}`, http.StatusBadRequest, 0},
+		{"Invalid method", http.MethodPost, "", http.StatusMethodNotAllowed, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/groups", bytes.NewBufferString(tc.body))
+			req.Header.Set(TenantHeader, "org")
+			resp := httptest.NewRecorder()
+
+			taskHandler.Groups(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var groups []dto.GroupStatus
+			if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(groups) != tc.expectedGroups {
+				t.Errorf("Expected %d groups, got %+v", tc.expectedGroups, groups)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Bandwidth(t *testing.T) {
+	taskService := services.NewTaskService(5)
+	taskHandler := NewTaskHandler(taskService)
+	if _, err := taskService.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 5, Width: 4}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		body              string
+		expectedStatus    int
+		expectedBandwidth int
+	}{
+		{"Read", http.MethodGet, "", http.StatusOK, 5},
+		{"Raise", http.MethodPut, `{"bandwidth": 8}`, http.StatusOK, 8},
+		{"Zero", http.MethodPut, `{"bandwidth": 0}`, http.StatusBadRequest, 8},
+		{"Below a queued width", http.MethodPut, `{"bandwidth": 3}`, http.StatusBadRequest, 8},
+		{"Invalid format", http.MethodPut, `{"bandwidth": "many"}`, http.StatusBadRequest, 8},
+		{"Invalid method", http.MethodPost, `{"bandwidth": 8}`, http.StatusMethodNotAllowed, 8},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/bandwidth", bytes.NewBufferString(tc.body))
+			resp := httptest.NewRecorder()
+
+			taskHandler.Bandwidth(resp, req)
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+			if got := taskService.GetBandwidth(); got != tc.expectedBandwidth {
+				t.Errorf("Expected bandwidth %d, got %d", tc.expectedBandwidth, got)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Usage(t *testing.T) {
+	taskService := services.NewTaskService(2)
+	if err := taskService.SetUnitPrices(models.UnitPrices{BandwidthTick: 0.5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taskHandler := NewTaskHandler(taskService)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(`[1, 1]`))
+	req.Header.Set(TenantHeader, "team-a")
+	taskHandler.SubmitTasks(httptest.NewRecorder(), req)
+	taskService.ExecuteSchedulingCycle()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"CSV", "?group_by=tenant,class&format=csv", http.StatusOK, "tenant,class,bandwidth_ticks,cost\nteam-a,,2,1\n"},
+		{"Invalid from", "?from=yesterday", http.StatusBadRequest, ""},
+		{"Invalid group_by", "?group_by=team", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			taskHandler.GetUsage(resp, httptest.NewRequest(http.MethodGet, "/usage"+tc.query, nil))
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+			if tc.expectedBody != "" && resp.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, resp.Body.String())
+			}
+		})
+	}
+
+	resp := httptest.NewRecorder()
+	taskHandler.GetUsage(resp, httptest.NewRequest(http.MethodGet, "/usage?tenant=team-a", nil))
+	var usage dto.UsageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(usage.Rows) != 1 || usage.TotalBandwidthTicks != 2 || usage.TotalCost != 1 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+}
+
+func TestTaskHandler_Audit(t *testing.T) {
+	taskService := services.NewTaskService(2)
+	taskHandler := NewTaskHandler(taskService)
+	auth, err := NewAuthenticator(models.APIKeyConfig{Keys: []models.APIKey{
+		{Name: "ops", Key: "admin-key-0000001", Role: models.RoleAdmin},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := taskService.SubmitTasks("team-a", []int{3, 4}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	index := taskService.GetStatus("team-a").ActiveTasks[0].Index
+
+	requests := []struct {
+		method  string
+		path    string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{http.MethodPost, "/scheduler", `{"strategy": "SRTF", "preemptive": false}`, taskHandler.SwitchScheduler},
+		{http.MethodPatch, "/tasks/" + strconv.Itoa(index), `{"priority": 5}`, taskHandler.Task},
+		{http.MethodDelete, "/tasks/" + strconv.Itoa(index), "", taskHandler.Task},
+		{http.MethodPut, "/groups", `{"path": "eng", "weight": 2}`, taskHandler.Groups},
+		{http.MethodPut, "/bandwidth", `{"bandwidth": 4}`, taskHandler.Bandwidth},
+		{http.MethodPut, "/quota", `{"max_queued_tasks": 5}`, taskHandler.Quota},
+	}
+	for _, request := range requests {
+		req := httptest.NewRequest(request.method, request.path, bytes.NewBufferString(request.body))
+		req.SetPathValue("index", strconv.Itoa(index))
+		req.Header.Set("Authorization", "Bearer admin-key-0000001")
+		req.Header.Set(TenantHeader, "team-a")
+		resp := httptest.NewRecorder()
+		auth.Require(AccessAdmin, request.handler)(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status code 200, got %d: %s", request.method, request.path, resp.Code, resp.Body)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedStatus  int
+		expectedActions []string
+	}{
+		{"All", "", http.StatusOK, []string{models.AuditSwitchScheduler, models.AuditUpdateTask, models.AuditCancelTask, models.AuditSetGroup, models.AuditSetBandwidth, models.AuditSetQuotas}},
+		{"By action", "?action=scheduler.switch&actor=key:ops&tenant=team-a", http.StatusOK, []string{models.AuditSwitchScheduler}},
+		{"Limit", "?limit=1", http.StatusOK, []string{models.AuditSetQuotas}},
+		{"Other actor", "?actor=anonymous", http.StatusOK, []string{}},
+		{"Invalid limit", "?limit=many", http.StatusBadRequest, nil},
+		{"Invalid from", "?from=yesterday", http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			taskHandler.GetAudit(resp, httptest.NewRequest(http.MethodGet, "/audit"+tc.query, nil))
+
+			if resp.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, resp.Code, resp.Body)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var entries []models.AuditEntry
+			if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(entries) != len(tc.expectedActions) {
+				t.Fatalf("Expected actions %v, got %+v", tc.expectedActions, entries)
+			}
+			for i, entry := range entries {
+				if entry.Action != tc.expectedActions[i] || entry.Actor != "key:ops" || entry.SourceIP != "192.0.2.1" {
+					t.Errorf("Expected %s by key:ops from 192.0.2.1, got %+v", tc.expectedActions[i], entry)
+				}
+			}
+		})
+	}
+
+	entries, _ := taskHandler.audit.Query(dto.AuditQuery{Action: models.AuditSwitchScheduler})
+	if entries[0].OldValue != (dto.SchedulerState{Strategy: "FIFO", Preemptive: true}) ||
+		entries[0].NewValue != (dto.SchedulerState{Strategy: "SRTF", Preemptive: false}) {
+		t.Errorf("Unexpected strategy change: %+v", entries[0])
+	}
+	entries, _ = taskHandler.audit.Query(dto.AuditQuery{Action: models.AuditSetBandwidth})
+	if entries[0].OldValue != 2 || entries[0].NewValue != 4 {
+		t.Errorf("Unexpected bandwidth change: %+v", entries[0])
+	}
+	entries, _ = taskHandler.audit.Query(dto.AuditQuery{Action: models.AuditSetQuotas, Tenant: "team-a"})
+	if entries[0].OldValue != (models.Quota{}) || entries[0].NewValue != (models.Quota{MaxQueuedTasks: 5}) {
+		t.Errorf("Unexpected quota change: %+v", entries[0])
+	}
+}
+
+func TestUsageRecord_FormulaInjection(t *testing.T) {
+	tests := []struct {
+		label    string
+		expected string
+	}{
+		{"web", "web"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"a=b", "a=b"},
+		{"", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			row := dto.UsageRow{Tenant: "team-a", Labels: map[string]string{"app": tc.label}, BandwidthTicks: 2, Cost: 1}
+			record := usageRecord(row, []string{"tenant", "label:app"})
+			if record[1] != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, record[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"scheduler-service/models"
+	"scheduler-service/utils"
+	"strconv"
+)
+
+// TenantHeader names the tenant a request acts for. Requests without it act
+// for models.DefaultTenant.
+const TenantHeader = "X-Tenant"
+
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// requestTenant returns the tenant of r, or writes a 400 response and
+// returns false if it is not a valid tenant name. A request bound to a
+// tenant acts for that tenant, and gets 403 if the header names another one.
+func requestTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenant := r.Header.Get(TenantHeader)
+	if bound, ok := boundTenant(r); ok {
+		if tenant != "" && tenant != bound {
+			utils.WriteErrorResponse(w, http.StatusForbidden, "Request is bound to tenant "+bound)
+			return "", false
+		}
+		tenant = bound
+	}
+	if tenant == "" {
+		return models.DefaultTenant, true
+	}
+	if !tenantName.MatchString(tenant) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest,
+			"Invalid tenant "+strconv.Quote(tenant)+", expected up to 64 letters, digits, '.', '_' or '-'")
+		return "", false
+	}
+	return tenant, true
+}
+
+// boundTenant returns the tenant r is bound to, if any: that of its API
+// key, or without a key the common name of its verified client
+// certificate. A key without a tenant, such as an admin or viewer key, is
+// not bound by the certificate it comes with.
+func boundTenant(r *http.Request) (string, bool) {
+	if key, ok := r.Context().Value(apiKeyContext{}).(models.APIKey); ok {
+		return key.Tenant, key.Tenant != ""
+	}
+	return certTenant(r)
+}
+
+// certTenant returns the common name of the verified client certificate of r.
+func certTenant(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return "", false
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	return name, name != ""
+}
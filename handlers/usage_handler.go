@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"scheduler-service/dto"
+	"scheduler-service/services"
+	"scheduler-service/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetUsage serves GET /usage: the bandwidth-ticks consumed in a time range
+// and their cost, as JSON or, with format=csv, as CSV.
+func (th *TaskHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Only GET method is allowed")
+		return
+	}
+
+	params := r.URL.Query()
+	query := dto.UsageQuery{Tenant: params.Get("tenant")}
+	if bound, ok := boundTenant(r); ok {
+		if query.Tenant != "" && query.Tenant != bound {
+			utils.WriteErrorResponse(w, http.StatusForbidden, "Request is bound to tenant "+bound)
+			return
+		}
+		query.Tenant = bound
+	}
+	for name, value := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if params.Get(name) == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, params.Get(name))
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid "+name+" time, expected RFC 3339")
+			return
+		}
+		*value = t
+	}
+	if groupBy := params.Get("group_by"); groupBy != "" {
+		query.GroupBy = strings.Split(groupBy, ",")
+	}
+
+	usage, err := th.taskService.GetUsage(query)
+	if errors.Is(err, services.ErrInvalidUsageQuery) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to build usage report")
+		return
+	}
+
+	if params.Get("format") != "csv" {
+		utils.WriteJSONResponse(w, http.StatusOK, usage)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage.csv"`)
+	writer := csv.NewWriter(w)
+	writer.Write(append(append([]string{}, usage.GroupBy...), "bandwidth_ticks", "cost"))
+	for _, row := range usage.Rows {
+		writer.Write(usageRecord(row, usage.GroupBy))
+	}
+	writer.Flush()
+}
+
+// usageRecord returns the CSV columns of row: its dimensions, then its
+// bandwidth-ticks and cost.
+func usageRecord(row dto.UsageRow, dimensions []string) []string {
+	record := make([]string, 0, len(dimensions)+2)
+	for _, dimension := range dimensions {
+		var value string
+		switch dimension {
+		case "tenant":
+			value = row.Tenant
+		case "job":
+			value = row.Job
+		case "class":
+			value = row.Class
+		default:
+			value = row.Labels[strings.TrimPrefix(dimension, "label:")]
+		}
+		record = append(record, csvText(value))
+	}
+	return append(record, strconv.Itoa(row.BandwidthTicks), strconv.FormatFloat(row.Cost, 'f', -1, 64))
+}
+
+// csvText keeps a spreadsheet from reading value as a formula: tenants and
+// labels come from clients, so a value starting like one is quoted with a
+// leading apostrophe.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -8,7 +8,12 @@ import (
 	"os"
 	"os/signal"
 	"scheduler-service/handlers"
+	"scheduler-service/models"
+	"scheduler-service/predict"
+	"scheduler-service/scheduler"
 	"scheduler-service/services"
+	"scheduler-service/tlsreload"
+	"strings"
 	"syscall"
 	"time"
 	// "github.com/gin-gonic/gin"
@@ -18,27 +23,196 @@ func main() {
 
 	port := flag.String("port", "8080", "Port for the HTTP server")
 	bandwidth := flag.Int("bandwidth", 5, "Bandwidth of the scheduler")
+	resources := flag.String("resources", "", "Per-cycle resource capacity, e.g. cpu=8,memory=32")
+	switchCost := flag.Int("switch-cost", 0, "Bandwidth a task loses when it joins the running set")
+	switchMode := flag.String("switch-mode", "resume", "Who pays the switch cost: resume (preempted tasks) or change (any task joining the running set)")
+	prediction := flag.String("prediction", "mean", "Statistic used to predict runtimes from completed tasks: mean, median or a quantile such as p90")
+	predictionFile := flag.String("prediction-file", "runtime_model.json", "File the learned runtime model is loaded from and saved to, empty to disable")
+	strategies := flag.String("strategies", "", "Comma-separated strategies to offer, starting with the first; empty offers all, starting with FIFO")
+	tenantPolicy := flag.String("tenant-policy", "fair", "How bandwidth is divided between tenants: fair (by weight) or priority (heaviest first)")
+	tenantWeights := flag.String("tenant-weights", "", "Tenant weights, e.g. team-a=3,team-b=1; unlisted tenants weigh 1")
+	partitions := flag.String("partitions", "", "Bandwidth partitions with their own strategies, e.g. interactive=SRTF:3:tier=interactive,batch=FIFO:2")
+	classes := flag.String("classes", "", "Service classes, most important first, as name=reserved[:cap[:preemptible]], e.g. critical=2,standard=0,best-effort=0:2:preemptible")
+	defaultClass := flag.String("default-class", "standard", "Service class of tasks submitted without one")
+	burst := flag.String("burst", "", "Burst credits of every tenant as rate:capacity, e.g. 0.5:20; empty gives none")
+	tenantBursts := flag.String("tenant-bursts", "", "Burst credits of single tenants, e.g. team-a=1:40,team-b=0:0")
+	unitPrice := flag.Float64("unit-price", 0, "Price of one bandwidth-tick in usage reports")
+	classPrices := flag.String("class-prices", "", "Prices of one bandwidth-tick per service class, e.g. critical=0.03,best-effort=0.005")
+	quotaFile := flag.String("quota-file", "", "JSON file with tenant quotas, empty for no limits")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate file to serve HTTPS with, reloaded when it changes; empty serves plain HTTP")
+	tlsKey := flag.String("tls-key", "", "PEM private key file of -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "PEM CA file to verify client certificates with (mutual TLS); empty accepts any client")
+	tlsClientAuth := flag.String("tls-client-auth", "require", "With -tls-client-ca: require a client certificate, or optional to serve clients without one")
+	auditFile := flag.String("audit-file", "audit.jsonl", "Append-only file of administrative actions, empty to keep them in memory only")
+	usageFile := flag.String("usage-file", "usage.jsonl", "Append-only file of the bandwidth-ticks used per minute, empty to keep them in memory only")
+	apiKeyFile := flag.String("api-key-file", "", "JSON file with API keys and their roles, empty to serve without authentication")
+	seed := flag.Int64("seed", 1, "Seed of the random generator used to simulate task failures")
 	flag.Parse()
 
-	taskService := services.NewTaskService(*bandwidth)
+	capacity, err := models.ParseResources(*resources)
+	if err != nil {
+		log.Fatal("Invalid -resources flag: ", err)
+	}
+
+	var strategyNames []string
+	if *strategies != "" {
+		strategyNames = strings.Split(*strategies, ",")
+	}
+	partitionList, err := scheduler.ParsePartitions(*partitions)
+	if err != nil {
+		log.Fatal("Invalid -partitions flag: ", err)
+	}
+	classList, err := scheduler.ParseServiceClasses(*classes)
+	if err != nil {
+		log.Fatal("Invalid -classes flag: ", err)
+	}
+	burstConfig, err := scheduler.ParseTokenBucket(*burst)
+	if err != nil {
+		log.Fatal("Invalid -burst flag: ", err)
+	}
+	engine, err := scheduler.NewEngine(scheduler.EngineOptions{
+		Bandwidth:    *bandwidth,
+		Strategies:   strategyNames,
+		TenantPolicy: *tenantPolicy,
+		Partitions:   partitionList,
+		Classes:      classList,
+		DefaultClass: *defaultClass,
+		Burst:        burstConfig,
+	})
+	if err != nil {
+		log.Fatal("Invalid engine flags: ", err)
+	}
+	weights, err := scheduler.ParseTenantWeights(*tenantWeights)
+	if err != nil {
+		log.Fatal("Invalid -tenant-weights flag: ", err)
+	}
+	bursts, err := scheduler.ParseTenantBursts(*tenantBursts)
+	if err != nil {
+		log.Fatal("Invalid -tenant-bursts flag: ", err)
+	}
+	taskService := services.NewTaskServiceWithEngine(engine)
+	for tenant, weight := range weights {
+		if err := taskService.SetTenantWeight(tenant, weight); err != nil {
+			log.Fatal("Invalid -tenant-weights flag: ", err)
+		}
+	}
+	for tenant, config := range bursts {
+		if err := taskService.SetTenantBurst(tenant, config); err != nil {
+			log.Fatal("Invalid -tenant-bursts flag: ", err)
+		}
+	}
+	taskService.SetCapacity(capacity)
+	taskService.SetSeed(*seed)
+	if err := taskService.SetContextSwitch(*switchCost, *switchMode); err != nil {
+		log.Fatal("Invalid context switch flags: ", err)
+	}
+	predictions, err := predict.New(*prediction)
+	if err != nil {
+		log.Fatal("Invalid -prediction flag: ", err)
+	}
+	if *predictionFile != "" {
+		if err := predictions.Load(*predictionFile); err != nil {
+			log.Fatal("Failed to load runtime model: ", err)
+		}
+	}
+	taskService.SetPredictionModel(predictions)
+	var quotas models.QuotaConfig
+	if *quotaFile != "" {
+		if quotas, err = services.LoadQuotaConfig(*quotaFile); err != nil {
+			log.Fatal("Failed to load quotas: ", err)
+		}
+		if err := taskService.SetQuotas(quotas); err != nil {
+			log.Fatal("Invalid quotas: ", err)
+		}
+	}
+	prices, err := models.ParseClassPrices(*classPrices)
+	if err != nil {
+		log.Fatal("Invalid -class-prices flag: ", err)
+	}
+	if err := taskService.SetUnitPrices(models.UnitPrices{BandwidthTick: *unitPrice, Classes: prices}); err != nil {
+		log.Fatal("Invalid -unit-price flag: ", err)
+	}
+	var auth *handlers.Authenticator
+	if *apiKeyFile != "" {
+		keys, err := handlers.LoadAPIKeys(*apiKeyFile)
+		if err != nil {
+			log.Fatal("Failed to load API keys: ", err)
+		}
+		if auth, err = handlers.NewAuthenticator(keys); err != nil {
+			log.Fatal("Invalid API keys: ", err)
+		}
+	} else {
+		log.Println("No -api-key-file given, serving without authentication")
+	}
+	audit := services.NewAuditLog()
+	if *auditFile != "" {
+		if audit, err = services.OpenAuditLog(*auditFile); err != nil {
+			log.Fatal("Failed to open audit log: ", err)
+		}
+	}
+	if *usageFile != "" {
+		if err := taskService.OpenUsageFile(*usageFile); err != nil {
+			log.Fatal("Failed to open usage file: ", err)
+		}
+	}
+	audit.RecordStartup(models.AuditSetBandwidth, *bandwidth)
+	audit.RecordStartup(models.AuditSetQuotas, quotas)
 	taskHandler := handlers.NewTaskHandler(taskService)
+	taskHandler.SetAuditLog(audit)
 
 	schedulerService := services.NewSchedulerService(taskService)
+	schedulerService.SetPredictionFile(*predictionFile)
 	schedulerService.Start()
 
+	recurringService := services.NewRecurringService(taskService)
+	scheduleHandler := handlers.NewScheduleHandler(recurringService)
+	recurringService.Start()
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/tasks", taskHandler.SubmitTasks)
-	mux.HandleFunc("/status", taskHandler.GetStatus)
-	mux.HandleFunc("/scheduler", taskHandler.SwitchScheduler)
+	mux.HandleFunc("/tasks", auth.Require(handlers.AccessSubmit, taskHandler.SubmitTasks))
+	mux.HandleFunc("/tasks/dead", auth.Require(handlers.AccessRead, taskHandler.GetDeadTasks))
+	mux.HandleFunc("/tasks/stream", auth.Require(handlers.AccessSubmit, taskHandler.StreamTasks))
+	mux.HandleFunc("/tasks/{index}", auth.Require(handlers.AccessSubmit, taskHandler.Task))
+	mux.HandleFunc("/tasks/{index}/move", auth.Require(handlers.AccessAdmin, taskHandler.MoveTask))
+	mux.HandleFunc("/status", auth.Require(handlers.AccessRead, taskHandler.GetStatus))
+	mux.HandleFunc("/metrics", auth.Require(handlers.AccessReadAll, taskHandler.GetMetrics))
+	mux.HandleFunc("/quota", auth.Require(handlers.AccessAdmin, taskHandler.Quota))
+	mux.HandleFunc("/groups", auth.Require(handlers.AccessAdmin, taskHandler.Groups))
+	mux.HandleFunc("/usage", auth.Require(handlers.AccessRead, taskHandler.GetUsage))
+	mux.HandleFunc("/audit", auth.Require(handlers.AccessReadAll, taskHandler.GetAudit))
+	mux.HandleFunc("/scheduler", auth.Require(handlers.AccessAdmin, taskHandler.SwitchScheduler))
+	mux.HandleFunc("/bandwidth", auth.Require(handlers.AccessAdmin, taskHandler.Bandwidth))
+	mux.HandleFunc("/schedules", auth.Require(handlers.AccessSubmit, scheduleHandler.Schedules))
+	mux.HandleFunc("/schedules/{id}", auth.Require(handlers.AccessSubmit, scheduleHandler.Schedule))
+	mux.HandleFunc("/schedules/{id}/pause", auth.Require(handlers.AccessSubmit, scheduleHandler.PauseSchedule))
+	mux.HandleFunc("/schedules/{id}/resume", auth.Require(handlers.AccessSubmit, scheduleHandler.ResumeSchedule))
 
 	server := &http.Server{
 		Addr:    ":" + *port,
 		Handler: mux,
 	}
+	if *tlsCert != "" || *tlsKey != "" || *tlsClientCA != "" {
+		reloader, err := tlsreload.New(tlsreload.Options{
+			CertFile:     *tlsCert,
+			KeyFile:      *tlsKey,
+			ClientCAFile: *tlsClientCA,
+			ClientAuth:   *tlsClientAuth,
+		})
+		if err != nil {
+			log.Fatal("Invalid TLS flags: ", err)
+		}
+		server.TLSConfig = reloader.TLSConfig()
+	}
 
 	go func() {
-		log.Printf("Server starting on port %s with bandwidth %d\n", *port, *bandwidth)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Server starting on port %s with bandwidth %d, TLS %t\n", *port, *bandwidth, server.TLSConfig != nil)
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatal("Failed to start server: ", err)
 		}
 	}()
@@ -46,11 +220,23 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	recurringService.Stop()
 	schedulerService.GracefulStop()
+	if *predictionFile != "" {
+		if err := taskService.SavePredictions(*predictionFile); err != nil {
+			log.Println("Failed to save runtime model: ", err)
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown: ", err)
 	}
+	if err := audit.Close(); err != nil {
+		log.Println("Failed to close audit log: ", err)
+	}
+	if err := taskService.CloseUsageFile(); err != nil {
+		log.Println("Failed to close usage file: ", err)
+	}
 	log.Println("Server exited")
 }
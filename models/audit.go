@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Actions recorded in the audit log.
+const (
+	AuditSwitchScheduler = "scheduler.switch"
+	AuditSetGroup        = "group.set"
+	AuditUpdateTask      = "task.update"
+	AuditMoveTask        = "task.move"
+	AuditCancelTask      = "task.cancel"
+	AuditSetBandwidth    = "bandwidth.set" // by PUT /bandwidth, or at startup when the flag changed since the last run
+	AuditSetQuotas       = "quota.set"     // of one tenant by PUT /quota, or of all at startup when the quota file changed
+)
+
+// AuditEntry records one administrative action.
+type AuditEntry struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor is "key:<name>" for an API key, "cert:<common name>" for a
+	// client certificate, "startup" for flags and files, or "anonymous".
+	Actor    string `json:"actor"`
+	SourceIP string `json:"source_ip,omitempty"`
+	Action   string `json:"action"`
+	Tenant   string `json:"tenant,omitempty"`
+	Target   string `json:"target,omitempty"` // e.g. the task index or group path
+	OldValue any    `json:"old_value,omitempty"`
+	NewValue any    `json:"new_value,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "fmt"
+
+// Role is what the holder of an API key may do.
+type Role string
+
+const (
+	RoleSubmitter Role = "submitter" // submits and manages the tasks of its tenant and reads its own status
+	RoleViewer    Role = "viewer"    // reads status and metrics
+	RoleAdmin     Role = "admin"     // everything, including strategies and group policies
+)
+
+// APIKey is one key of an API key file.
+type APIKey struct {
+	Name string `json:"name"` // shown in logs instead of the key
+	Key  string `json:"key"`
+	Role Role   `json:"role"`
+	// Tenant binds the key to one tenant; a key without a tenant acts for
+	// the tenant of the X-Tenant header. Submitter keys need one.
+	Tenant string `json:"tenant,omitempty"`
+	// HMACSecret, if set, requires every request with the key to be signed.
+	HMACSecret string `json:"hmac_secret,omitempty"`
+}
+
+func (k APIKey) Validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("API key needs a name")
+	}
+	if len(k.Key) < 16 {
+		return fmt.Errorf("API key %s must be at least 16 characters", k.Name)
+	}
+	switch k.Role {
+	case RoleSubmitter:
+		if k.Tenant == "" {
+			return fmt.Errorf("submitter key %s needs a tenant", k.Name)
+		}
+	case RoleViewer, RoleAdmin:
+	default:
+		return fmt.Errorf("API key %s has role %q, expected %s, %s or %s", k.Name, k.Role, RoleSubmitter, RoleViewer, RoleAdmin)
+	}
+	return nil
+}
+
+// APIKeyConfig is the content of an API key file.
+type APIKeyConfig struct {
+	Keys []APIKey `json:"keys"`
+}
+
+func (c APIKeyConfig) Validate() error {
+	names := make(map[string]bool, len(c.Keys))
+	keys := make(map[string]bool, len(c.Keys))
+	for _, key := range c.Keys {
+		if err := key.Validate(); err != nil {
+			return err
+		}
+		if names[key.Name] || keys[key.Key] {
+			return fmt.Errorf("duplicate API key %s", key.Name)
+		}
+		names[key.Name] = true
+		keys[key.Key] = true
+	}
+	return nil
+}
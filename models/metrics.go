@@ -0,0 +1,35 @@
+package models
+
+// Metrics aggregates what the scheduler has done since the service started.
+type Metrics struct {
+	CurrentTime        int `json:"current_time"`
+	CompletedTasks     int `json:"completed_tasks"`
+	AllocatedBandwidth int `json:"allocated_bandwidth"`
+	ContextSwitches    int `json:"context_switches"`
+	SwitchOverhead     int `json:"switch_overhead"` // bandwidth units lost to context switches
+	MissedDeadlines    int `json:"missed_deadlines"`
+	CancelledTasks     int `json:"cancelled_tasks"`
+
+	// 运行时间估计的准确度，按已结束的任务统计
+	EstimatedTasks int     `json:"estimated_tasks"`
+	PredictedTasks int     `json:"predicted_tasks"` // estimates predicted from completed tasks with the same labels
+	EstimateError  float64 `json:"estimate_error"`  // mean absolute error between estimate and actual duration
+	Underestimated int     `json:"underestimated"`
+	Extensions     int     `json:"extensions"`
+	KilledTasks    int     `json:"killed_tasks"`
+
+	Classes []ClassMetrics `json:"classes,omitempty"`
+
+	BurstCredits   map[string]float64 `json:"burst_credits,omitempty"` // credits left per tenant
+	BurstBandwidth int                `json:"burst_bandwidth"`         // units tenants used above their fair share
+}
+
+// ClassMetrics reports the bandwidth one service class got.
+type ClassMetrics struct {
+	Name               string  `json:"name"`
+	AllocatedBandwidth int     `json:"allocated_bandwidth"`
+	Utilization        float64 `json:"utilization"`   // share of all bandwidth of the elapsed cycles
+	ActiveCycles       int     `json:"active_cycles"` // cycles the class had queued tasks
+	HonoredCycles      int     `json:"honored_cycles"`
+	HonoredRate        float64 `json:"honored_rate"` // share of active cycles offered at least the reservation
+}
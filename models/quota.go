@@ -0,0 +1,67 @@
+package models
+
+import "fmt"
+
+const (
+	QuotaReject = "reject" // a submission over the quota fails with 429
+	QuotaPark   = "park"   // it waits in the pending-admission queue until it fits
+)
+
+// DefaultMaxParkedTasks is how many tasks of a tenant may wait parked
+// when its quota sets no limit.
+const DefaultMaxParkedTasks = 10000
+
+// Quota limits what one tenant may have queued. Zero limits are unlimited.
+type Quota struct {
+	MaxQueuedTasks     int    `json:"max_queued_tasks"`
+	MaxOutstandingWork int    `json:"max_outstanding_work"` // sum of RemainingTime of queued tasks
+	MaxTaskDuration    int    `json:"max_task_duration"`
+	OnExceed           string `json:"on_exceed"` // reject (default) or park
+	// MaxParkedTasks bounds the parked tasks with park; a submission that
+	// would park more fails with 429. 0 is DefaultMaxParkedTasks.
+	MaxParkedTasks int `json:"max_parked_tasks"`
+}
+
+func (q Quota) Validate() error {
+	if q.MaxQueuedTasks < 0 || q.MaxOutstandingWork < 0 || q.MaxTaskDuration < 0 || q.MaxParkedTasks < 0 {
+		return fmt.Errorf("quota limits cannot be negative")
+	}
+	if q.OnExceed != "" && q.OnExceed != QuotaReject && q.OnExceed != QuotaPark {
+		return fmt.Errorf("on_exceed must be %s or %s, got %s", QuotaReject, QuotaPark, q.OnExceed)
+	}
+	return nil
+}
+
+// ParkedLimit returns how many tasks may wait parked.
+func (q Quota) ParkedLimit() int {
+	if q.MaxParkedTasks == 0 {
+		return DefaultMaxParkedTasks
+	}
+	return q.MaxParkedTasks
+}
+
+// QuotaConfig is the quota of every tenant, as read from a quota file.
+type QuotaConfig struct {
+	Default Quota            `json:"default"`
+	Tenants map[string]Quota `json:"tenants"`
+}
+
+func (c QuotaConfig) Validate() error {
+	if err := c.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for tenant, quota := range c.Tenants {
+		if err := quota.Validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+	return nil
+}
+
+// For returns the quota of tenant.
+func (c QuotaConfig) For(tenant string) Quota {
+	if quota, exists := c.Tenants[tenant]; exists {
+		return quota
+	}
+	return c.Default
+}
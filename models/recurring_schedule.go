@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	OverlapSkip  = "skip"  // drop a run while the previous one is still running
+	OverlapQueue = "queue" // start a run as soon as the previous one finishes
+)
+
+const (
+	RunSubmitted = "submitted"
+	RunSkipped   = "skipped"
+	RunQueued    = "queued"
+	RunFailed    = "failed"
+)
+
+// RecurringSchedule submits the same tasks on a cron expression or a fixed interval.
+type RecurringSchedule struct {
+	ID          string        `json:"id"`
+	Tenant      string        `json:"tenant"`
+	Cron        string        `json:"cron,omitempty"`
+	Interval    string        `json:"interval,omitempty"`
+	TaskCount   int           `json:"task_count"`
+	Strategy    string        `json:"strategy,omitempty"`
+	Group       string        `json:"group,omitempty"` // schedules/<id> with a strategy
+	Overlap     string        `json:"overlap"`
+	Paused      bool          `json:"paused"`
+	NextRun     time.Time     `json:"next_run"`
+	CreatedTime time.Time     `json:"created_time"`
+	History     []ScheduleRun `json:"history"`
+}
+
+type ScheduleRun struct {
+	Time   time.Time `json:"time"`
+	JobID  string    `json:"job_id,omitempty"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resources maps a resource dimension such as "cpu" or "memory" to a number of units.
+type Resources map[string]int
+
+// ParseResources parses a comma separated list like "cpu=8,memory=32".
+func ParseResources(s string) (Resources, error) {
+	resources := make(Resources)
+	if strings.TrimSpace(s) == "" {
+		return resources, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid resource %q, expected name=units", pair)
+		}
+		units, err := strconv.Atoi(value)
+		if err != nil || units < 0 {
+			return nil, fmt.Errorf("invalid units for resource %s: %q", name, value)
+		}
+		resources[name] = units
+	}
+	return resources, nil
+}
+
+// Fits reports whether every dimension requested by r is available in free.
+func (r Resources) Fits(free Resources) bool {
+	for name, units := range r {
+		if units > free[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Sub removes the units requested by other from r in place.
+func (r Resources) Sub(other Resources) {
+	for name, units := range other {
+		r[name] -= units
+	}
+}
+
+func (r Resources) Clone() Resources {
+	clone := make(Resources, len(r))
+	for name, units := range r {
+		clone[name] = units
+	}
+	return clone
+}
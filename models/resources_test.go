@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseResources(t *testing.T) {
+	resources, err := ParseResources("cpu=8, memory=32")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resources["cpu"] != 8 || resources["memory"] != 32 {
+		t.Errorf("Expected cpu=8 memory=32, got %v", resources)
+	}
+
+	for _, input := range []string{"cpu", "cpu=x", "=4", "cpu=-1"} {
+		if _, err := ParseResources(input); err == nil {
+			t.Errorf("Expected error for %q, got nil", input)
+		}
+	}
+}
+
+func TestResourcesFits(t *testing.T) {
+	free := Resources{"cpu": 4, "memory": 8}
+
+	tests := []struct {
+		name     string
+		demand   Resources
+		expected bool
+	}{
+		{"No demand", nil, true},
+		{"Within capacity", Resources{"cpu": 2, "memory": 8}, true},
+		{"Over one dimension", Resources{"cpu": 5}, false},
+		{"Unknown dimension", Resources{"gpu": 1}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.demand.Fits(free); got != tc.expected {
+				t.Errorf("Expected Fits to be %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "fmt"
+
+const (
+	RestartFull       = "full"       // a retry starts from the full duration
+	RestartCheckpoint = "checkpoint" // a retry keeps the progress made before the failure
+)
+
+// RetryPolicy decides what happens to a task after a failed cycle.
+type RetryPolicy struct {
+	MaxAttempts  int    `json:"max_attempts"`  // including the first attempt
+	BackoffTicks int    `json:"backoff_ticks"` // ticks to wait before the next attempt
+	Restart      string `json:"restart"`
+}
+
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttempts < 0 || p.BackoffTicks < 0 {
+		return fmt.Errorf("max_attempts and backoff_ticks cannot be negative")
+	}
+	if p.Restart != "" && p.Restart != RestartFull && p.Restart != RestartCheckpoint {
+		return fmt.Errorf("restart must be %s or %s, got %s", RestartFull, RestartCheckpoint, p.Restart)
+	}
+	return nil
+}
+
+// ShouldRetry reports whether a task that has failed the given number of times gets another attempt.
+func (p *RetryPolicy) ShouldRetry(failures int) bool {
+	return p != nil && failures < p.MaxAttempts
+}
@@ -4,4 +4,7 @@ type ScheduleResult struct {
 	Time           int   `json:"time"`
 	TaskIndexes    []int `json:"task_indexes"`
 	RemainingTimes []int `json:"remaining_times"`
+	Allocations    []int `json:"allocations"`
+	FailedIndexes  []int `json:"failed_indexes,omitempty"`
+	Overhead       int   `json:"overhead,omitempty"`
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	SpeedupLinear = "linear"
+	SpeedupAmdahl = "amdahl"
+	SpeedupTable  = "table"
+)
+
+// SpeedupModel describes how much faster a task runs on several bandwidth units.
+// A nil model is linear: n units remove n units of RemainingTime.
+type SpeedupModel struct {
+	Model          string    `json:"model"`
+	SerialFraction float64   `json:"serial_fraction,omitempty"` // amdahl
+	Table          []float64 `json:"table,omitempty"`           // speedup with 1, 2, ... units
+}
+
+func (m *SpeedupModel) Validate() error {
+	if m == nil {
+		return nil
+	}
+	switch m.Model {
+	case SpeedupLinear:
+	case SpeedupAmdahl:
+		if m.SerialFraction < 0 || m.SerialFraction > 1 {
+			return fmt.Errorf("serial_fraction must be between 0 and 1, got %v", m.SerialFraction)
+		}
+	case SpeedupTable:
+		if len(m.Table) == 0 {
+			return fmt.Errorf("speedup table cannot be empty")
+		}
+		for i, speedup := range m.Table {
+			if speedup < 0 || (i > 0 && speedup < m.Table[i-1]) {
+				return fmt.Errorf("speedup table must be non-negative and non-decreasing")
+			}
+		}
+		// 全为0的表永远不会推进任务
+		if m.Table[len(m.Table)-1] <= 0 {
+			return fmt.Errorf("speedup table must end with a positive speedup")
+		}
+	default:
+		return fmt.Errorf("unsupported speedup model: %s", m.Model)
+	}
+	return nil
+}
+
+// Speedup returns the work done in one cycle with the given units.
+func (m *SpeedupModel) Speedup(units int) float64 {
+	if units <= 0 {
+		return 0
+	}
+	if m == nil {
+		return float64(units)
+	}
+	switch m.Model {
+	case SpeedupAmdahl:
+		return 1 / (m.SerialFraction + (1-m.SerialFraction)/float64(units))
+	case SpeedupTable:
+		return m.Table[min(units, len(m.Table))-1]
+	default:
+		return float64(units)
+	}
+}
+
+// unitsFor returns the fewest units, up to limit, that do at least work in one cycle.
+func (m *SpeedupModel) unitsFor(work float64, limit int) int {
+	if m == nil {
+		return min(int(math.Ceil(work)), limit)
+	}
+	for units := 1; units < limit; units++ {
+		if m.Speedup(units) >= work {
+			return units
+		}
+	}
+	return limit
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpeedupModel_Speedup(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    *SpeedupModel
+		units    int
+		expected float64
+	}{
+		{"Nil model is linear", nil, 3, 3},
+		{"Linear", &SpeedupModel{Model: SpeedupLinear}, 4, 4},
+		{"Amdahl", &SpeedupModel{Model: SpeedupAmdahl, SerialFraction: 0.5}, 4, 1.6},
+		{"Table", &SpeedupModel{Model: SpeedupTable, Table: []float64{1, 1.5, 1.8}}, 2, 1.5},
+		{"Table beyond last entry", &SpeedupModel{Model: SpeedupTable, Table: []float64{1, 1.5, 1.8}}, 5, 1.8},
+		{"No units", &SpeedupModel{Model: SpeedupAmdahl, SerialFraction: 0.1}, 0, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.model.Speedup(tc.units); math.Abs(got-tc.expected) > 1e-9 {
+				t.Errorf("Expected speedup %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSpeedupModel_Validate(t *testing.T) {
+	invalid := []*SpeedupModel{
+		{Model: "quadratic"},
+		{Model: SpeedupAmdahl, SerialFraction: 1.5},
+		{Model: SpeedupTable},
+		{Model: SpeedupTable, Table: []float64{2, 1}},
+		{Model: SpeedupTable, Table: []float64{0, 0, 0}},
+	}
+	for _, model := range invalid {
+		if err := model.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, got nil", model)
+		}
+	}
+
+	var nilModel *SpeedupModel
+	if err := nilModel.Validate(); err != nil {
+		t.Errorf("Expected nil model to be valid, got %v", err)
+	}
+}
@@ -1,25 +1,86 @@
 package models
 
 import (
+	"fmt"
+	"math"
 	"sync/atomic"
 	"time"
 )
 
-var globalIndex int32
+// DefaultTenant owns the tasks of requests that name no tenant.
+const DefaultTenant = "default"
+
+// What happens to a task that is still running when its estimate runs out.
+const (
+	UnderestimateExtend = "extend"
+	UnderestimateKill   = "kill"
+)
 
 type Task struct {
 	Index         int
+	Duration      int
 	RemainingTime int
 	IsCompleted   bool
 	CreatedTime   time.Time
+	JobID         string
+	Tenant        string
+	Group         string // 租户内的调度组路径，如 eng/search
+	Class         string // 服务类别，如 critical
+	Labels        map[string]string
+	Resources     Resources
+	Width         int       // 刚性任务每个周期必须恰好获得的带宽，0表示不限
+	Allocated     int       // 最近一个周期分配到的带宽
+	Executions    int       // 已经运行过的周期数
+	LastCycle     int       // 最近一次运行的调度周期
+	Overhead      int       // 最近一个周期因上下文切换损失的带宽
+	Worked        int       // 最近一个周期完成的工作量
+	NotBefore     int       // 最早可以运行的调度周期
+	StartAt       time.Time // 最早可以运行的时间
+	Priority      int       // 数值越大越优先
+	Rank          int       // 管理员调整的同优先级内次序，越小越靠前
+	Deadline      int       // 应当完成的调度周期，0表示没有截止时间
+
+	// 故障模拟与重试
+	FailureProbability float64
+	FailureScript      []bool // 第n个运行周期是否失败，优先于FailureProbability
+	Retry              *RetryPolicy
+	Failures           int
+	IsFailed           bool
+	FailureReason      string
+
+	// 用户声明的运行时间估计；调度策略只能看到估计，完成与否取决于Duration
+	Estimate        int
+	OnUnderestimate string // extend（默认）或kill
+	Extensions      int    // 估计不足时已延长的次数
+	Killed          bool
+	Predicted       bool // Estimate由历史完成记录预测得到
+	Revised         int  // 运行中修正后的总时长估计，0表示沿用Estimate
+
+	// 可伸缩任务：并行度范围及加速模型
+	MinParallelism int
+	MaxParallelism int
+	Speedup        *SpeedupModel
+
+	progress float64 // 加速比产生的不足一个时间单位的进度
 }
 
+// IDAllocator hands out task indexes. Every engine owns one, so the indexes
+// of independent engines never interfere. It is safe for concurrent use.
+type IDAllocator struct {
+	next atomic.Int64
+}
+
+// Next returns the next unused index, starting at 0.
+func (a *IDAllocator) Next() int {
+	return int(a.next.Add(1) - 1)
+}
+
+// NewTask returns a task of the given duration. Its index and creation time
+// are left to the engine that admits it.
 func NewTask(duration int) *Task {
-	idx := atomic.AddInt32(&globalIndex, 1) - 1
 	return &Task{
-		Index:         int(idx),
+		Duration:      duration,
 		RemainingTime: duration,
-		CreatedTime:   time.Now(),
 	}
 }
 
@@ -31,3 +92,128 @@ func (t *Task) Execute(timeSlice int) {
 		t.IsCompleted = true
 	}
 }
+
+// Units returns the bandwidth the task takes out of available, or false
+// when a rigid task cannot get its full width or a malleable task its
+// minimum parallelism.
+func (t *Task) Units(available int) (int, bool) {
+	if t.Width > 0 {
+		return t.Width, available >= t.Width
+	}
+
+	limit := available
+	if t.MaxParallelism > 0 {
+		limit = min(limit, t.MaxParallelism)
+	}
+	if t.MinParallelism > 0 && limit < t.MinParallelism {
+		return t.MinParallelism, false
+	}
+	units := t.Speedup.unitsFor(float64(t.RemainingTime)-t.progress, limit)
+	return max(units, t.MinParallelism), true
+}
+
+// Fail records a cycle in which the task held units but failed, making no progress.
+func (t *Task) Fail(units int, reason string) {
+	t.Allocated = units
+	t.Overhead = 0
+	t.Worked = 0
+	t.Executions++
+	t.IsFailed = true
+	t.FailureReason = reason
+}
+
+// IsFinished reports whether the task left the queue, either completed or failed.
+func (t *Task) IsFinished() bool {
+	return t.IsCompleted || t.IsFailed
+}
+
+// Restart clears the failure so the task can be queued again. Unless it
+// resumes from its checkpoint, all progress is lost.
+func (t *Task) Restart(fromCheckpoint bool) {
+	t.IsFailed = false
+	if !fromCheckpoint {
+		t.RemainingTime = t.Duration
+		t.progress = 0
+	}
+}
+
+// Progress returns the work the task would complete with the given units this cycle.
+func (t *Task) Progress(units int) float64 {
+	return min(t.Speedup.Speedup(units)+t.progress, float64(max(t.RemainingTime, 0)))
+}
+
+// Run executes the task with the given bandwidth units for one cycle,
+// reducing RemainingTime according to its speedup model.
+func (t *Task) Run(units int) {
+	t.RunWithOverhead(units, 0)
+}
+
+// RunWithOverhead runs the task on units of bandwidth of which the first
+// overhead units are lost to a context switch.
+func (t *Task) RunWithOverhead(units, overhead int) {
+	t.Allocated = units
+	t.Overhead = overhead
+	t.Executions++
+	units -= overhead
+	remaining := t.RemainingTime
+	if t.Speedup == nil {
+		t.Execute(units)
+	} else {
+		work := t.Speedup.Speedup(units) + t.progress
+		done := int(work)
+		t.progress = work - float64(done)
+		t.Execute(done)
+	}
+	t.Worked = remaining - t.RemainingTime
+}
+
+// CyclesAt estimates how many cycles the task needs to finish when it keeps
+// the given units every cycle, going by its estimated remaining time.
+func (t *Task) CyclesAt(units int) int {
+	speedup := t.Speedup.Speedup(units)
+	if speedup <= 0 {
+		return math.MaxInt
+	}
+	return int(math.Ceil(max(float64(t.EstimatedRemaining())-t.progress, 0) / speedup))
+}
+
+// EstimatedRemaining is the remaining time as far as schedulers can tell:
+// the current estimate minus the work done so far. Without an estimate
+// the actual remaining time is used.
+func (t *Task) EstimatedRemaining() int {
+	if t.Estimate <= 0 {
+		return t.RemainingTime
+	}
+	return max(t.currentEstimate()-t.Elapsed(), 0)
+}
+
+// Elapsed is the work the task has done so far.
+func (t *Task) Elapsed() int {
+	return t.Duration - t.RemainingTime
+}
+
+func (t *Task) currentEstimate() int {
+	if t.Revised > 0 {
+		return t.Revised
+	}
+	return t.Estimate * (t.Extensions + 1)
+}
+
+// EnforceEstimate handles a task that used up its estimate without
+// completing: it is killed, or its estimate is extended by the declared
+// estimate once more.
+func (t *Task) EnforceEstimate() {
+	if t.Estimate <= 0 || t.IsFinished() || t.EstimatedRemaining() > 0 {
+		return
+	}
+	if t.OnUnderestimate == UnderestimateKill {
+		t.IsFailed = true
+		t.Killed = true
+		t.FailureReason = fmt.Sprintf("exceeded estimate of %d", t.Estimate)
+		return
+	}
+	t.Extensions++
+	if t.Revised > 0 {
+		t.Revised += t.Estimate
+	}
+}
@@ -44,3 +44,146 @@ func TestTaskExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestTaskUnits(t *testing.T) {
+	tests := []struct {
+		name          string
+		task          Task
+		available     int
+		expectedUnits int
+		expectedOk    bool
+	}{
+		{"Fractional share", Task{RemainingTime: 10}, 4, 4, true},
+		{"Capped by remaining time", Task{RemainingTime: 2}, 4, 2, true},
+		{"Rigid task fits", Task{RemainingTime: 2, Width: 3}, 4, 3, true},
+		{"Rigid task blocked", Task{RemainingTime: 10, Width: 3}, 2, 3, false},
+		{"Capped by max parallelism", Task{RemainingTime: 10, MaxParallelism: 2}, 4, 2, true},
+		{"Below min parallelism", Task{RemainingTime: 10, MinParallelism: 3}, 2, 3, false},
+		{"Min parallelism near completion", Task{RemainingTime: 1, MinParallelism: 2}, 4, 2, true},
+		{"Fewest units that finish", Task{RemainingTime: 3, Speedup: &SpeedupModel{Model: SpeedupTable, Table: []float64{1, 3, 4}}}, 4, 2, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			units, ok := tc.task.Units(tc.available)
+			if units != tc.expectedUnits || ok != tc.expectedOk {
+				t.Errorf("Expected (%d, %v), got (%d, %v)",
+					tc.expectedUnits, tc.expectedOk, units, ok)
+			}
+		})
+	}
+}
+
+func TestTaskRunWithSpeedup(t *testing.T) {
+	task := NewTask(10)
+	task.Speedup = &SpeedupModel{Model: SpeedupAmdahl, SerialFraction: 0.5}
+
+	// 4个单位的加速比为1.6，小数部分累积到下一个周期
+	task.Run(4)
+	if task.RemainingTime != 9 || task.Allocated != 4 {
+		t.Errorf("Expected RemainingTime 9 with 4 units allocated, got %d with %d",
+			task.RemainingTime, task.Allocated)
+	}
+
+	task.Run(4)
+	if task.RemainingTime != 7 {
+		t.Errorf("Expected carried progress to complete a unit, got RemainingTime %d", task.RemainingTime)
+	}
+}
+
+func TestTaskCyclesAt(t *testing.T) {
+	task := NewTask(10)
+	if cycles := task.CyclesAt(3); cycles != 4 {
+		t.Errorf("Expected 4 cycles at 3 units, got %d", cycles)
+	}
+
+	task.Speedup = &SpeedupModel{Model: SpeedupTable, Table: []float64{1, 2.5}}
+	if cycles := task.CyclesAt(2); cycles != 4 {
+		t.Errorf("Expected 4 cycles with speedup 2.5, got %d", cycles)
+	}
+}
+
+func TestTaskFailAndRestart(t *testing.T) {
+	tests := []struct {
+		name           string
+		fromCheckpoint bool
+		expectedRemain int
+	}{
+		{"Restart from full duration", false, 10},
+		{"Restart from checkpoint", true, 6},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			task := NewTask(10)
+			task.Run(4)
+			task.Fail(4, "boom")
+
+			if !task.IsFailed || !task.IsFinished() || task.RemainingTime != 6 {
+				t.Fatalf("Expected failed task with no progress in the failed cycle, got %+v", task)
+			}
+
+			task.Restart(tc.fromCheckpoint)
+			if task.IsFailed || task.IsFinished() {
+				t.Error("Expected restarted task to be queued again")
+			}
+			if task.RemainingTime != tc.expectedRemain {
+				t.Errorf("Expected RemainingTime to be %d, got %d", tc.expectedRemain, task.RemainingTime)
+			}
+		})
+	}
+}
+
+func TestTaskEnforceEstimate(t *testing.T) {
+	tests := []struct {
+		name            string
+		onUnderestimate string
+		expectedKilled  bool
+		expectedRemain  int
+	}{
+		{"Extend by the estimate", UnderestimateExtend, false, 4},
+		{"Kill", UnderestimateKill, true, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			task := NewTask(10)
+			task.Estimate = 4
+			task.OnUnderestimate = tc.onUnderestimate
+
+			task.Run(3)
+			task.EnforceEstimate()
+			if task.EstimatedRemaining() != 1 || task.IsFinished() {
+				t.Fatalf("Expected 1 estimated unit left, got %d", task.EstimatedRemaining())
+			}
+
+			task.Run(1)
+			task.EnforceEstimate()
+			if task.Killed != tc.expectedKilled || task.IsFailed != tc.expectedKilled {
+				t.Errorf("Expected killed %v, got %+v", tc.expectedKilled, task)
+			}
+			if task.EstimatedRemaining() != tc.expectedRemain {
+				t.Errorf("Expected %d estimated units left, got %d", tc.expectedRemain, task.EstimatedRemaining())
+			}
+		})
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var none *RetryPolicy
+	if none.ShouldRetry(0) {
+		t.Error("Expected no retry without a policy")
+	}
+
+	policy := &RetryPolicy{MaxAttempts: 3}
+	if !policy.ShouldRetry(2) || policy.ShouldRetry(3) {
+		t.Error("Expected retries to stop after 3 attempts")
+	}
+
+	if err := (&RetryPolicy{Restart: "sometimes"}).Validate(); err == nil {
+		t.Error("Expected error for unknown restart mode")
+	}
+	if err := (&RetryPolicy{MaxAttempts: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative max_attempts")
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnitPrices prices the bandwidth-ticks a tenant consumed: one bandwidth
+// unit allocated for one scheduling cycle.
+type UnitPrices struct {
+	BandwidthTick float64            `json:"bandwidth_tick"`
+	Classes       map[string]float64 `json:"classes,omitempty"` // overrides per service class
+}
+
+// For returns the price of one bandwidth-tick of a task of the class.
+func (p UnitPrices) For(class string) float64 {
+	if price, exists := p.Classes[class]; exists {
+		return price
+	}
+	return p.BandwidthTick
+}
+
+func (p UnitPrices) Validate() error {
+	if p.BandwidthTick < 0 {
+		return fmt.Errorf("unit price cannot be negative")
+	}
+	for class, price := range p.Classes {
+		if price < 0 {
+			return fmt.Errorf("unit price of class %s cannot be negative", class)
+		}
+	}
+	return nil
+}
+
+// ParseClassPrices parses a comma separated list like "critical=0.03,best-effort=0.005".
+func ParseClassPrices(s string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	if strings.TrimSpace(s) == "" {
+		return prices, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		class, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || class == "" {
+			return nil, fmt.Errorf("invalid class price %q, expected class=price", pair)
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			return nil, fmt.Errorf("invalid price for class %s: %q", class, value)
+		}
+		prices[class] = price
+	}
+	return prices, nil
+}
@@ -0,0 +1,136 @@
+package predict
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// window bounds how many recent durations are kept for each label set.
+const window = 50
+
+const (
+	Mean   = "mean"
+	Median = "median"
+)
+
+// Model predicts task durations from the durations of completed tasks that
+// carried the same labels. It is not safe for concurrent use.
+type Model struct {
+	statistic string
+	quantile  float64
+	samples   map[string][]int
+}
+
+// New returns an empty model using statistic: "mean", "median" or a
+// quantile such as "p90".
+func New(statistic string) (*Model, error) {
+	m := &Model{statistic: statistic, samples: make(map[string][]int)}
+	switch {
+	case statistic == Mean:
+	case statistic == Median:
+		m.quantile = 0.5
+	case strings.HasPrefix(statistic, "p"):
+		p, err := strconv.Atoi(statistic[1:])
+		if err != nil || p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("invalid quantile %q", statistic)
+		}
+		m.quantile = float64(p) / 100
+	default:
+		return nil, fmt.Errorf("unknown statistic %q, use mean, median or pNN", statistic)
+	}
+	return m, nil
+}
+
+// Observe records the duration of a completed task.
+func (m *Model) Observe(labels map[string]string, duration int) {
+	key := Key(labels)
+	if key == "" || duration <= 0 {
+		return
+	}
+	samples := append(m.samples[key], duration)
+	if len(samples) > window {
+		samples = samples[len(samples)-window:]
+	}
+	m.samples[key] = samples
+}
+
+// Predict returns the expected total duration of a task with labels that
+// has already run for elapsed time units, using only the completions that
+// took longer than that. It returns false without such history.
+func (m *Model) Predict(labels map[string]string, elapsed int) (int, bool) {
+	var samples []int
+	for _, duration := range m.samples[Key(labels)] {
+		if duration > elapsed {
+			samples = append(samples, duration)
+		}
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	if m.statistic == Mean {
+		sum := 0
+		for _, duration := range samples {
+			sum += duration
+		}
+		return int(math.Ceil(float64(sum) / float64(len(samples)))), true
+	}
+	sort.Ints(samples)
+	i := int(math.Ceil(m.quantile*float64(len(samples)))) - 1
+	return samples[max(i, 0)], true
+}
+
+// Key identifies a label set independently of map order; empty labels have no key.
+func Key(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type file struct {
+	Statistic string           `json:"statistic"`
+	Samples   map[string][]int `json:"samples"`
+}
+
+// Save writes the learned durations to path, replacing it atomically.
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(file{Statistic: m.statistic, Samples: m.samples}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads durations saved by Save into the model. A missing file leaves
+// the model empty.
+func (m *Model) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved file
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid model file %s: %w", path, err)
+	}
+	for key, samples := range saved.Samples {
+		m.samples[key] = samples
+	}
+	return nil
+}
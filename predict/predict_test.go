@@ -0,0 +1,88 @@
+package predict
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	for _, statistic := range []string{"", "mode", "p0", "p100", "px"} {
+		if _, err := New(statistic); err == nil {
+			t.Errorf("Expected error for %q, got nil", statistic)
+		}
+	}
+}
+
+func TestModel_Predict(t *testing.T) {
+	labels := map[string]string{"app": "etl", "size": "large"}
+	durations := []int{2, 4, 6, 8, 20}
+
+	tests := []struct {
+		name      string
+		statistic string
+		elapsed   int
+		expected  int
+	}{
+		{"Mean", Mean, 0, 8},
+		{"Median", Median, 0, 6},
+		{"Quantile", "p80", 0, 8},
+		{"Conditional on elapsed", Median, 5, 8},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := New(tc.statistic)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, duration := range durations {
+				model.Observe(labels, duration)
+			}
+
+			// 标签顺序不影响匹配
+			prediction, ok := model.Predict(map[string]string{"size": "large", "app": "etl"}, tc.elapsed)
+			if !ok || prediction != tc.expected {
+				t.Errorf("Expected prediction %d, got %d (%v)", tc.expected, prediction, ok)
+			}
+		})
+	}
+}
+
+func TestModel_NoHistory(t *testing.T) {
+	model, _ := New(Mean)
+	model.Observe(map[string]string{"app": "etl"}, 5)
+
+	if _, ok := model.Predict(map[string]string{"app": "web"}, 0); ok {
+		t.Error("Expected no prediction for unseen labels")
+	}
+	if _, ok := model.Predict(map[string]string{"app": "etl"}, 5); ok {
+		t.Error("Expected no prediction once the task outlived every completion")
+	}
+	if _, ok := model.Predict(nil, 0); ok {
+		t.Error("Expected no prediction without labels")
+	}
+}
+
+func TestModel_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	labels := map[string]string{"app": "etl"}
+
+	model, _ := New(Mean)
+	model.Observe(labels, 3)
+	model.Observe(labels, 5)
+	if err := model.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored, _ := New(Mean)
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if prediction, ok := restored.Predict(labels, 0); !ok || prediction != 4 {
+		t.Errorf("Expected restored prediction 4, got %d (%v)", prediction, ok)
+	}
+
+	if err := restored.Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Expected a missing file to be ignored, got %v", err)
+	}
+}
@@ -1,25 +1,32 @@
 package scheduler
 
 import (
-	"container/heap"
+	"fmt"
 	"scheduler-service/models"
 )
 
-type HeapInterface interface {
-	heap.Interface
-	Len() int
+type BaseScheduler struct {
+	queue       *TaskQueue
+	name        string
+	config      *Config
+	reservation *reservation
+	preemptive  bool
+	running     []models.Task // 非抢占模式下已经开始运行的任务
 }
 
-type BaseScheduler struct {
-	heap HeapInterface
-	name string
+// reservation holds bandwidth for a task that could not get its full width
+// or minimum parallelism.
+type reservation struct {
+	index int
+	width int
 }
 
-func NewBaseScheduler(h HeapInterface, name string) *BaseScheduler {
-	heap.Init(h)
+func NewBaseScheduler(less Less, name string) *BaseScheduler {
 	return &BaseScheduler{
-		heap: h,
-		name: name,
+		queue:      NewTaskQueue(less),
+		name:       name,
+		config:     &Config{},
+		preemptive: true,
 	}
 }
 
@@ -27,57 +34,277 @@ func (b *BaseScheduler) GetName() string {
 	return b.name
 }
 
+func (b *BaseScheduler) Reserved() int {
+	if b.reservation == nil {
+		return 0
+	}
+	return b.reservation.width
+}
+
+func (b *BaseScheduler) SetConfig(config *Config) {
+	b.config = config
+}
+
+func (b *BaseScheduler) IsPreemptive() bool {
+	return b.preemptive
+}
+
+// SetPreemptive switches between preemptive scheduling and running every
+// started task to completion before unstarted tasks get bandwidth.
+func (b *BaseScheduler) SetPreemptive(preemptive bool) {
+	if b.preemptive == preemptive {
+		return
+	}
+
+	var tasks []models.Task
+	for {
+		task, exists := b.GetNextTask()
+		if !exists {
+			break
+		}
+		tasks = append(tasks, task)
+	}
+	b.preemptive = preemptive
+	for _, task := range tasks {
+		b.AddTasks(task)
+	}
+}
+
 func (b *BaseScheduler) Schedule(bandwidth int) []*models.Task {
+	b.beginCycle()
 	var scheduledTasks []*models.Task
-	usedBandwidth := 0
+	free := b.config.Capacity.Clone()
 	var tempTasks []models.Task
 
-	for b.heap.Len() > 0 && usedBandwidth < bandwidth {
-		task := heap.Pop(b.heap).(models.Task)
-		if task.IsCompleted {
-			continue
+	// A wide task blocked in the previous cycle gets its width held back,
+	// so smaller tasks can fill gaps without starving it. It still only runs
+	// if its width fits the bandwidth of this call, which is all the group
+	// may use; a width above the group's share is lent by its siblings, see
+	// group.schedule.
+	reserved := b.reservation
+	b.reservation = nil
+	budget := bandwidth
+	if reserved != nil {
+		budget -= reserved.width
+	}
+	usedBandwidth := 0
+
+	place := func(task *models.Task) bool {
+		if reserved != nil && task.Index == reserved.index {
+			// 释放预留的带宽，任务按正常路径分配
+			budget += reserved.width
+			reserved = nil
+		}
+
+		// Tasks that do not fit the remaining capacity wait for the next cycle
+		if !task.Resources.Fits(free) {
+			return false
 		}
 
-		availableBandwidth := bandwidth - usedBandwidth
+		availableBandwidth := budget - usedBandwidth
 		if availableBandwidth <= 0 {
-			tempTasks = append(tempTasks, task)
-			break
+			return false
 		}
 
-		allocatedTime := min(task.RemainingTime, availableBandwidth)
-		task.Execute(allocatedTime)
+		allocatedTime, ok := b.units(task, availableBandwidth)
+		if !ok {
+			if b.reservation == nil {
+				b.reservation = &reservation{index: task.Index, width: max(task.Width, task.MinParallelism)}
+			}
+			return false
+		}
+		b.run(task, allocatedTime)
 		usedBandwidth += allocatedTime
+		free.Sub(task.Resources)
+		return true
+	}
 
-		scheduledTasks = append(scheduledTasks, &task)
+	// 非抢占模式下已开始的任务优先获得带宽
+	for _, task := range b.takeRunning() {
+		if place(&task) {
+			scheduledTasks = append(scheduledTasks, &task)
+		}
+		b.requeue(task)
+	}
+
+	for b.queue.Len() > 0 && (usedBandwidth < budget || reserved != nil) {
+		task, _ := b.queue.Pop()
+		if place(&task) {
+			scheduledTasks = append(scheduledTasks, &task)
+		}
 
-		if !task.IsCompleted {
-			tempTasks = append(tempTasks, task)
+		if !task.IsFinished() {
+			if b.holdsBandwidth(task) {
+				b.running = append(b.running, task)
+			} else {
+				tempTasks = append(tempTasks, task)
+			}
 		}
 	}
 
 	for _, task := range tempTasks {
-		heap.Push(b.heap, task)
+		b.queue.Push(task)
 	}
 
 	return scheduledTasks
 }
 
+// beginCycle advances the cycle counter shared by the schedulers of one manager.
+func (b *BaseScheduler) beginCycle() {
+	b.config.cycle++
+}
+
+// run executes task for one cycle, unless the configured failure model fails it.
+func (b *BaseScheduler) run(task *models.Task, units int) {
+	overhead := b.switchOverhead(task, units)
+	task.LastCycle = b.config.cycle
+	if b.config.Failure != nil && b.config.Failure(task) {
+		task.Fail(units, fmt.Sprintf("failed on execution %d", task.Executions+1))
+		return
+	}
+	task.RunWithOverhead(units, overhead)
+	if task.Predicted && b.config.Estimator != nil && !task.IsCompleted {
+		task.Revised = b.config.Estimator(task)
+	}
+	task.EnforceEstimate()
+}
+
+// units returns the allocation for task out of available, leaving room for
+// the context switch overhead it is about to pay.
+func (b *BaseScheduler) units(task *models.Task, available int) (int, bool) {
+	if task.Width > 0 {
+		return task.Units(available)
+	}
+	overhead := b.switchOverhead(task, available)
+	units, ok := task.Units(available - overhead)
+	return units + overhead, ok
+}
+
+// switchOverhead returns the units of this cycle's allocation lost to a
+// context switch: a started task resuming after it was preempted pays the
+// cost, and with SwitchOnChange so does a task starting for the first time.
+func (b *BaseScheduler) switchOverhead(task *models.Task, units int) int {
+	if b.config.SwitchCost <= 0 {
+		return 0
+	}
+	if task.Executions == 0 && b.config.SwitchMode != SwitchOnChange {
+		return 0
+	}
+	if task.Executions > 0 && task.LastCycle == b.config.cycle-1 {
+		return 0
+	}
+	return min(b.config.SwitchCost, units)
+}
+
+// reserveWide records a reservation for task, which did not run, if it is
+// wider than the whole bandwidth of the call and no other task holds one,
+// so that the group above lends it bandwidth.
+func (b *BaseScheduler) reserveWide(task models.Task, bandwidth int) {
+	if width := max(task.Width, task.MinParallelism); width > bandwidth && b.reservation == nil {
+		b.reservation = &reservation{index: task.Index, width: width}
+	}
+}
+
+// holdsBandwidth reports whether a started task keeps running ahead of the queue.
+func (b *BaseScheduler) holdsBandwidth(task models.Task) bool {
+	return !b.preemptive && task.Executions > 0
+}
+
+func (b *BaseScheduler) takeRunning() []models.Task {
+	running := b.running
+	b.running = nil
+	return running
+}
+
+// requeue puts an unfinished task back after a cycle.
+func (b *BaseScheduler) requeue(task models.Task) {
+	if task.IsFinished() {
+		return
+	}
+	if b.holdsBandwidth(task) {
+		b.running = append(b.running, task)
+		return
+	}
+	b.queue.Push(task)
+}
+
 func (b *BaseScheduler) AddTasks(task models.Task) {
-	if !task.IsCompleted {
-		heap.Push(b.heap, task)
+	b.requeue(task)
+}
+
+// AddBatch adds many tasks at once, heapifying large batches in linear time.
+func (b *BaseScheduler) AddBatch(tasks []models.Task) {
+	queued := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		switch {
+		case task.IsFinished():
+		case b.holdsBandwidth(task):
+			b.running = append(b.running, task)
+		default:
+			queued = append(queued, task)
+		}
 	}
+	b.queue.PushAll(queued)
 }
 
 func (b *BaseScheduler) GetNextTask() (models.Task, bool) {
-	for b.heap.Len() > 0 {
-		task := heap.Pop(b.heap).(models.Task)
-		if !task.IsCompleted {
-			return task, true
-		}
+	if len(b.running) > 0 {
+		task := b.running[0]
+		b.running = b.running[1:]
+		return task, true
+	}
+	return b.queue.Pop()
+}
+
+// Peek returns the task that would run first without removing it.
+func (b *BaseScheduler) Peek() (models.Task, bool) {
+	if len(b.running) > 0 {
+		return b.running[0], true
 	}
-	return models.Task{}, false
+	return b.queue.Peek()
 }
 
 func (b *BaseScheduler) GetTasksLen() int {
-	return b.heap.Len()
+	return b.queue.Len() + len(b.running)
+}
+
+// Tasks returns a copy of the queued tasks in scheduling order, without
+// changing the queue.
+func (b *BaseScheduler) Tasks() []models.Task {
+	tasks := make([]models.Task, 0, b.GetTasksLen())
+	tasks = append(tasks, b.running...)
+	return append(tasks, b.queue.Tasks()...)
+}
+
+// Head returns copies of the first n tasks in scheduling order.
+func (b *BaseScheduler) Head(n int) []models.Task {
+	tasks := make([]models.Task, 0, min(n, b.GetTasksLen()))
+	tasks = append(tasks, b.running[:min(n, len(b.running))]...)
+	return append(tasks, b.queue.Head(n-len(tasks))...)
+}
+
+// UpdateTask applies update to the queued task with the given index and
+// restores the queue order. It returns false if no such task is queued.
+func (b *BaseScheduler) UpdateTask(index int, update func(task *models.Task)) bool {
+	for i := range b.running {
+		if b.running[i].Index == index {
+			update(&b.running[i])
+			return true
+		}
+	}
+	return b.queue.Update(index, update)
+}
+
+// RemoveTask removes and returns the queued task with the given index.
+func (b *BaseScheduler) RemoveTask(index int) (models.Task, bool) {
+	for i, task := range b.running {
+		if task.Index == index {
+			b.running = append(b.running[:i], b.running[i+1:]...)
+			return task, true
+		}
+	}
+	if b.reservation != nil && b.reservation.index == index {
+		b.reservation = nil
+	}
+	return b.queue.Remove(index)
 }
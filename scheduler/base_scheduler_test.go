@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+)
+
+func TestBaseScheduler_RigidTaskReservation(t *testing.T) {
+	srtf := NewSRTFScheduler()
+	srtf.AddTasks(models.Task{Index: 0, RemainingTime: 8, Width: 4})
+	srtf.AddTasks(models.Task{Index: 1, RemainingTime: 2})
+	srtf.AddTasks(models.Task{Index: 2, RemainingTime: 2})
+
+	// 第一个周期：宽任务只剩1个单位可用，被阻塞并获得预留
+	scheduledTasks := srtf.Schedule(5)
+	if len(scheduledTasks) != 2 || scheduledTasks[0].Index != 1 || scheduledTasks[1].Index != 2 {
+		t.Fatalf("Expected tasks 1 and 2 to run in the first cycle, got %v", scheduledTasks)
+	}
+
+	// 新的短任务不能再抢占宽任务的预留带宽
+	srtf.AddTasks(models.Task{Index: 3, RemainingTime: 1})
+	srtf.AddTasks(models.Task{Index: 4, RemainingTime: 2})
+
+	scheduledTasks = srtf.Schedule(5)
+	if len(scheduledTasks) != 2 {
+		t.Fatalf("Expected 2 scheduled tasks, got %d", len(scheduledTasks))
+	}
+	if scheduledTasks[0].Index != 3 || scheduledTasks[0].Allocated != 1 {
+		t.Errorf("Expected task 3 to fill the one-unit gap, got %+v", scheduledTasks[0])
+	}
+	if scheduledTasks[1].Index != 0 || scheduledTasks[1].Allocated != 4 || scheduledTasks[1].RemainingTime != 4 {
+		t.Errorf("Expected wide task 0 to run with its full width, got %+v", scheduledTasks[1])
+	}
+}
+
+func TestBaseScheduler_RigidTaskNeverSplit(t *testing.T) {
+	fifo := NewFIFOScheduler()
+	fifo.AddTasks(models.Task{Index: 0, RemainingTime: 3, Width: 3})
+
+	if scheduledTasks := fifo.Schedule(2); len(scheduledTasks) != 0 {
+		t.Fatalf("Expected rigid task not to run on 2 units, got %v", scheduledTasks)
+	}
+
+	scheduledTasks := fifo.Schedule(3)
+	if len(scheduledTasks) != 1 || !scheduledTasks[0].IsCompleted {
+		t.Fatalf("Expected rigid task to complete with 3 units, got %v", scheduledTasks)
+	}
+}
+
+func TestBaseScheduler_MalleableTaskReservation(t *testing.T) {
+	fifo := NewFIFOScheduler()
+	fifo.AddTasks(models.Task{Index: 0, RemainingTime: 3})
+	fifo.AddTasks(models.Task{Index: 1, RemainingTime: 5, MinParallelism: 3})
+
+	// 第一个周期只剩2个单位，低于最小并行度，任务1获得预留
+	scheduledTasks := fifo.Schedule(5)
+	if len(scheduledTasks) != 1 || scheduledTasks[0].Index != 0 {
+		t.Fatalf("Expected only task 0 to run in the first cycle, got %v", scheduledTasks)
+	}
+
+	scheduledTasks = fifo.Schedule(5)
+	if len(scheduledTasks) != 1 || scheduledTasks[0].Allocated != 5 || !scheduledTasks[0].IsCompleted {
+		t.Fatalf("Expected the reserved malleable task to complete on 5 units, got %+v", scheduledTasks)
+	}
+
+	// 份额一直低于最小并行度时任务不能以0个单位运行
+	fifo.AddTasks(models.Task{Index: 2, RemainingTime: 5, MinParallelism: 3})
+	for cycle := 0; cycle < 3; cycle++ {
+		if scheduledTasks := fifo.Schedule(2); len(scheduledTasks) != 0 {
+			t.Fatalf("Expected task 2 not to run on 2 units, got %+v", scheduledTasks)
+		}
+	}
+}
+
+func TestBaseScheduler_FailureModel(t *testing.T) {
+	fifo := NewFIFOScheduler()
+	fifo.SetConfig(&Config{Failure: func(task *models.Task) bool { return task.Index == 0 }})
+	fifo.AddTasks(models.Task{Index: 0, RemainingTime: 10})
+	fifo.AddTasks(models.Task{Index: 1, RemainingTime: 10})
+
+	scheduledTasks := fifo.Schedule(5)
+	if len(scheduledTasks) != 1 || !scheduledTasks[0].IsFailed || scheduledTasks[0].RemainingTime != 10 {
+		t.Fatalf("Expected task 0 to fail without progress, got %v", scheduledTasks)
+	}
+
+	// 失败的任务离开调度队列
+	if fifo.GetTasksLen() != 1 {
+		t.Errorf("Expected only task 1 to stay queued, got %d", fifo.GetTasksLen())
+	}
+}
+
+func TestBaseScheduler_ContextSwitchCost(t *testing.T) {
+	tests := []struct {
+		name            string
+		mode            string
+		expectedFirst   int // 第一个周期的上下文切换开销
+		expectedResumed int // 被抢占后恢复时的开销
+	}{
+		{"Resume only", SwitchOnResume, 0, 2},
+		{"Any change", SwitchOnChange, 2, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srtf := NewSRTFScheduler()
+			srtf.SetConfig(&Config{SwitchCost: 2, SwitchMode: tc.mode})
+			srtf.AddTasks(models.Task{Index: 0, RemainingTime: 20})
+
+			scheduledTasks := srtf.Schedule(5)
+			if scheduledTasks[0].Overhead != tc.expectedFirst {
+				t.Errorf("Expected first cycle overhead %d, got %d", tc.expectedFirst, scheduledTasks[0].Overhead)
+			}
+
+			// 连续运行不产生开销
+			scheduledTasks = srtf.Schedule(5)
+			if scheduledTasks[0].Overhead != 0 {
+				t.Errorf("Expected no overhead while running, got %d", scheduledTasks[0].Overhead)
+			}
+			remaining := scheduledTasks[0].RemainingTime
+
+			// 更短的任务抢占一个周期，恰好用完全部带宽
+			srtf.AddTasks(models.Task{Index: 1, RemainingTime: 5 - tc.expectedFirst})
+			srtf.Schedule(5)
+
+			scheduledTasks = srtf.Schedule(5)
+			task := scheduledTasks[0]
+			if task.Index != 0 || task.Overhead != tc.expectedResumed {
+				t.Fatalf("Expected task 0 to resume with overhead %d, got %+v", tc.expectedResumed, task)
+			}
+			if task.RemainingTime != remaining-5+tc.expectedResumed {
+				t.Errorf("Expected lost units to make no progress, got RemainingTime %d", task.RemainingTime)
+			}
+		})
+	}
+}
+
+func TestBaseScheduler_NonPreemptive(t *testing.T) {
+	srtf := NewSRTFScheduler()
+	srtf.SetPreemptive(false)
+	srtf.AddTasks(models.Task{Index: 0, RemainingTime: 10})
+	srtf.Schedule(5)
+
+	srtf.AddTasks(models.Task{Index: 1, RemainingTime: 1})
+	if srtf.GetTasksLen() != 2 {
+		t.Fatalf("Expected 2 queued tasks, got %d", srtf.GetTasksLen())
+	}
+
+	scheduledTasks := srtf.Schedule(5)
+	if len(scheduledTasks) != 1 || scheduledTasks[0].Index != 0 || !scheduledTasks[0].IsCompleted {
+		t.Fatalf("Expected started task 0 to run to completion first, got %v", scheduledTasks)
+	}
+
+	// 切回抢占模式后任务仍然保留
+	srtf.SetPreemptive(true)
+	scheduledTasks = srtf.Schedule(5)
+	if len(scheduledTasks) != 1 || scheduledTasks[0].Index != 1 {
+		t.Fatalf("Expected task 1 to run after task 0 finished, got %v", scheduledTasks)
+	}
+}
+
+func TestBaseScheduler_UpdateTask(t *testing.T) {
+	srtf := NewSRTFScheduler()
+	for i, remaining := range []int{2, 4, 6, 8} {
+		srtf.AddTasks(models.Task{Index: i, RemainingTime: remaining})
+	}
+
+	// 提高优先级后无需重建堆即排到最前
+	if !srtf.UpdateTask(3, func(task *models.Task) { task.Priority = 1 }) {
+		t.Fatal("Expected task 3 to be updated")
+	}
+	if srtf.UpdateTask(9, func(task *models.Task) {}) {
+		t.Error("Expected unknown task not to be updated")
+	}
+	if srtf.GetTasksLen() != 4 || len(srtf.Tasks()) != 4 {
+		t.Fatalf("Expected 4 queued tasks, got %d", srtf.GetTasksLen())
+	}
+
+	scheduledTasks := srtf.Schedule(8)
+	if len(scheduledTasks) != 1 || scheduledTasks[0].Index != 3 {
+		t.Errorf("Expected task 3 to take the whole cycle, got %v", scheduledTasks)
+	}
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"math/rand"
+	"scheduler-service/models"
+	"testing"
+	"time"
+)
+
+// benchmarkQueued is the number of tasks queued in the large-queue benchmarks.
+const benchmarkQueued = 1000000
+
+var benchmarkStrategies = []struct {
+	name string
+	new  func() Scheduler
+}{
+	{"FIFO", func() Scheduler { return NewFIFOScheduler() }},
+	{"SRTF", func() Scheduler { return NewSRTFScheduler() }},
+}
+
+func benchmarkTasks(n int) []models.Task {
+	rng := rand.New(rand.NewSource(1))
+	base := time.Now()
+	tasks := make([]models.Task, n)
+	for i := range tasks {
+		remaining := 1 + rng.Intn(100)
+		tasks[i] = models.Task{
+			Index:         i,
+			Duration:      remaining,
+			RemainingTime: remaining,
+			CreatedTime:   base.Add(time.Duration(i)),
+		}
+	}
+	return tasks
+}
+
+// BenchmarkSchedule measures one scheduling cycle with 1M tasks queued.
+func BenchmarkSchedule(b *testing.B) {
+	tasks := benchmarkTasks(benchmarkQueued)
+	for _, strategy := range benchmarkStrategies {
+		b.Run(strategy.name, func(b *testing.B) {
+			scheduler := strategy.new()
+			scheduler.AddBatch(tasks)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				scheduler.Schedule(5)
+			}
+		})
+	}
+}
+
+// BenchmarkAddBatch measures merging 1M submitted tasks into an empty scheduler.
+func BenchmarkAddBatch(b *testing.B) {
+	tasks := benchmarkTasks(benchmarkQueued)
+	for _, strategy := range benchmarkStrategies {
+		b.Run(strategy.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				strategy.new().AddBatch(tasks)
+			}
+		})
+	}
+}
+
+// BenchmarkAddTasks measures pushing 1M tasks one at a time, for comparison with AddBatch.
+func BenchmarkAddTasks(b *testing.B) {
+	tasks := benchmarkTasks(benchmarkQueued)
+	for _, strategy := range benchmarkStrategies {
+		b.Run(strategy.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scheduler := strategy.new()
+				for _, task := range tasks {
+					scheduler.AddTasks(task)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkHead measures listing the first tasks of 1M queued tasks, as
+// status snapshots do after every cycle.
+func BenchmarkHead(b *testing.B) {
+	tasks := benchmarkTasks(benchmarkQueued)
+	for _, strategy := range benchmarkStrategies {
+		b.Run(strategy.name, func(b *testing.B) {
+			scheduler := strategy.new()
+			scheduler.AddBatch(tasks)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				scheduler.Head(1000)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+)
+
+// bestFitWindow bounds how many queued tasks are considered for packing in one cycle.
+const bestFitWindow = 128
+
+// BestFitScheduler packs tasks against every resource dimension of the capacity.
+// Among the oldest queued tasks it repeatedly picks the one that leaves the least
+// free capacity behind, so small tasks fill the gaps around large ones.
+type BestFitScheduler struct {
+	*BaseScheduler
+}
+
+func NewBestFitScheduler() *BestFitScheduler {
+	baseScheduler := NewBaseScheduler(fifoLess, "BESTFIT")
+	return &BestFitScheduler{
+		BaseScheduler: baseScheduler,
+	}
+}
+
+func (s *BestFitScheduler) Schedule(bandwidth int) []*models.Task {
+	s.beginCycle()
+	s.reservation = nil
+	// 非抢占模式下已开始的任务排在候选列表前面，并优先放置
+	candidates := s.takeRunning()
+	started := len(candidates)
+	for s.queue.Len() > 0 && len(candidates) < started+bestFitWindow {
+		task, _ := s.queue.Pop()
+		candidates = append(candidates, task)
+	}
+
+	capacity := s.config.Capacity
+	free := capacity.Clone()
+	placed := make([]bool, len(candidates))
+	var scheduledTasks []*models.Task
+	usedBandwidth := 0
+
+	for usedBandwidth < bandwidth {
+		best := -1
+		bestScore := 0.0
+		for i, task := range candidates {
+			if placed[i] || !task.Resources.Fits(free) {
+				continue
+			}
+			if _, ok := s.units(&task, bandwidth-usedBandwidth); !ok {
+				continue
+			}
+			score := leftover(capacity, free, task.Resources)
+			if i < started {
+				best = i
+				break
+			}
+			if best == -1 || score < bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		task := &candidates[best]
+		allocatedTime, _ := s.units(task, bandwidth-usedBandwidth)
+		s.run(task, allocatedTime)
+		usedBandwidth += allocatedTime
+		free.Sub(task.Resources)
+		placed[best] = true
+
+		scheduled := *task
+		scheduledTasks = append(scheduledTasks, &scheduled)
+	}
+
+	for i, task := range candidates {
+		if !placed[i] {
+			s.reserveWide(task, bandwidth)
+		}
+		s.requeue(task)
+	}
+
+	return scheduledTasks
+}
+
+// leftover is the normalized capacity that stays free after placing demand.
+func leftover(capacity, free, demand models.Resources) float64 {
+	score := 0.0
+	for name, total := range capacity {
+		if total == 0 {
+			continue
+		}
+		score += float64(free[name]-demand[name]) / float64(total)
+	}
+	return score
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+	"time"
+)
+
+func TestBestFitScheduler_GetName(t *testing.T) {
+	bestFit := NewBestFitScheduler()
+	if bestFit.GetName() != "BESTFIT" {
+		t.Errorf("Expected scheduler name to be BESTFIT, got %s", bestFit.GetName())
+	}
+}
+
+func TestBestFitScheduler_Schedule(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name            string
+		capacity        models.Resources
+		tasks           []models.Task
+		bandwidth       int
+		expectedIndexes []int
+	}{
+		{
+			name:     "Pack tightest task first",
+			capacity: models.Resources{"cpu": 4, "memory": 8},
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 2, CreatedTime: now, Resources: models.Resources{"cpu": 1, "memory": 1}},
+				{Index: 1, RemainingTime: 2, CreatedTime: now.Add(time.Millisecond), Resources: models.Resources{"cpu": 3, "memory": 7}},
+				{Index: 2, RemainingTime: 2, CreatedTime: now.Add(2 * time.Millisecond), Resources: models.Resources{"cpu": 2, "memory": 2}},
+			},
+			bandwidth:       10,
+			expectedIndexes: []int{1, 0},
+		},
+		{
+			name:     "Skip tasks that do not fit",
+			capacity: models.Resources{"cpu": 4},
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 2, CreatedTime: now, Resources: models.Resources{"cpu": 3}},
+				{Index: 1, RemainingTime: 2, CreatedTime: now.Add(time.Millisecond), Resources: models.Resources{"cpu": 2}},
+				{Index: 2, RemainingTime: 2, CreatedTime: now.Add(2 * time.Millisecond), Resources: models.Resources{"cpu": 1}},
+			},
+			bandwidth:       10,
+			expectedIndexes: []int{0, 2},
+		},
+		{
+			name:     "Bandwidth still limits progress",
+			capacity: models.Resources{"cpu": 4},
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 5, CreatedTime: now, Resources: models.Resources{"cpu": 1}},
+				{Index: 1, RemainingTime: 5, CreatedTime: now.Add(time.Millisecond), Resources: models.Resources{"cpu": 1}},
+			},
+			bandwidth:       5,
+			expectedIndexes: []int{0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bestFit := NewBestFitScheduler()
+			bestFit.SetConfig(&Config{Capacity: tc.capacity})
+			for _, task := range tc.tasks {
+				bestFit.AddTasks(task)
+			}
+
+			scheduledTasks := bestFit.Schedule(tc.bandwidth)
+
+			if len(scheduledTasks) != len(tc.expectedIndexes) {
+				t.Fatalf("Expected %d scheduled tasks, got %d",
+					len(tc.expectedIndexes), len(scheduledTasks))
+			}
+			for i, task := range scheduledTasks {
+				if task.Index != tc.expectedIndexes[i] {
+					t.Errorf("Expected task index %d, got %d",
+						tc.expectedIndexes[i], task.Index)
+				}
+			}
+		})
+	}
+}
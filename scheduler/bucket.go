@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TokenBucket configures the burst credits of a tenant: while it has
+// credits, the tenant may take up to that many bandwidth units above its
+// fair share in a cycle, paying one credit for each.
+type TokenBucket struct {
+	Rate     float64 // credits added every cycle
+	Capacity float64 // most credits a tenant can save up
+}
+
+// bucket holds the credits of one tenant, shared by its trees of all classes.
+type bucket struct {
+	TokenBucket
+	credits float64
+	burst   int // 超出公平份额使用的带宽总量
+}
+
+func newBucket(config TokenBucket) *bucket {
+	return &bucket{TokenBucket: config, credits: config.Capacity}
+}
+
+func (b *bucket) refill() {
+	b.credits = min(b.credits+b.Rate, b.Capacity)
+}
+
+// spend charges the units a tenant used above its fair share.
+func (b *bucket) spend(units int) {
+	if units <= 0 {
+		return
+	}
+	b.credits = max(b.credits-float64(units), 0)
+	b.burst += units
+}
+
+func (t TokenBucket) Validate() error {
+	for _, value := range []float64{t.Rate, t.Capacity} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("burst rate and capacity must be finite numbers")
+		}
+	}
+	if t.Rate < 0 || t.Capacity < 0 {
+		return fmt.Errorf("burst rate and capacity cannot be negative")
+	}
+	return nil
+}
+
+// ParseTokenBucket parses "rate:capacity", e.g. "0.5:20".
+func ParseTokenBucket(s string) (TokenBucket, error) {
+	var config TokenBucket
+	if strings.TrimSpace(s) == "" {
+		return config, nil
+	}
+	rate, capacity, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return config, fmt.Errorf("invalid burst %q, expected rate:capacity", s)
+	}
+	var err error
+	if config.Rate, err = strconv.ParseFloat(rate, 64); err != nil {
+		return config, fmt.Errorf("invalid burst rate %q", rate)
+	}
+	if config.Capacity, err = strconv.ParseFloat(capacity, 64); err != nil {
+		return config, fmt.Errorf("invalid burst capacity %q", capacity)
+	}
+	return config, config.Validate()
+}
+
+// ParseTenantBursts parses a comma separated list like "team-a=1:20,team-b=0.5:10".
+func ParseTenantBursts(s string) (map[string]TokenBucket, error) {
+	bursts := make(map[string]TokenBucket)
+	if strings.TrimSpace(s) == "" {
+		return bursts, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid tenant burst %q, expected tenant=rate:capacity", pair)
+		}
+		config, err := ParseTokenBucket(value)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", name, err)
+		}
+		bursts[name] = config
+	}
+	return bursts, nil
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestParseTenantBursts(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]TokenBucket
+		wantErr  bool
+	}{
+		{"Empty", "", map[string]TokenBucket{}, false},
+		{"Bursts", "team-a=1:20,team-b=0.5:10",
+			map[string]TokenBucket{"team-a": {Rate: 1, Capacity: 20}, "team-b": {Rate: 0.5, Capacity: 10}}, false},
+		{"Missing capacity", "team-a=1", nil, true},
+		{"Negative rate", "team-a=-1:20", nil, true},
+		{"NaN rate", "team-a=NaN:20", nil, true},
+		{"Infinite capacity", "team-a=1:+Inf", nil, true},
+		{"Missing tenant", "=1:20", nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bursts, err := ParseTenantBursts(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if len(bursts) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, bursts)
+			}
+			for name, config := range tc.expected {
+				if bursts[name] != config {
+					t.Errorf("Expected %s to get %+v, got %+v", name, config, bursts[name])
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_Burst(t *testing.T) {
+	engine, _ := NewEngine(EngineOptions{Bandwidth: 4})
+	if err := engine.SetTenantBurst("a", TokenBucket{Rate: 1, Capacity: 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		for i := 0; i < 10; i++ {
+			task := engine.NewTask(10)
+			task.Tenant = name
+			task.MaxParallelism = 1
+			engine.Tenant(name).GetCurrentScheduler().AddTasks(*task)
+		}
+	}
+
+	// 额度用完后回到公平份额，之后按速率恢复
+	expected := []struct {
+		allocated int
+		credits   float64
+	}{{4, 0}, {2, 1}, {4, 0}}
+	for cycle, want := range expected {
+		allocated := 0
+		for _, task := range engine.Schedule() {
+			if task.Tenant == "a" {
+				allocated += task.Allocated
+			}
+		}
+		engine.Advance()
+		credits, _, _ := engine.Credits("a")
+		if allocated != want.allocated || credits != want.credits {
+			t.Errorf("Cycle %d: expected tenant a to get %d units with %v credits left, got %d and %v",
+				cycle, want.allocated, want.credits, allocated, credits)
+		}
+	}
+
+	if _, burst, _ := engine.Credits("a"); burst != 4 {
+		t.Errorf("Expected 4 units above the fair share, got %d", burst)
+	}
+	if _, _, ok := engine.Credits("b"); ok {
+		t.Error("Expected tenant b to have no burst credits")
+	}
+}
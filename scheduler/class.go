@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"fmt"
+	"scheduler-service/models"
+	"strconv"
+	"strings"
+)
+
+// ServiceClass is a class of tasks, such as critical, standard or
+// best-effort, with its own tree of tenants and a guaranteed share of every
+// cycle. Classes come in order of importance: bandwidth beyond the
+// reservations goes to the first class with queued tasks, up to its cap.
+type ServiceClass struct {
+	Name string
+	// Reserved is the bandwidth held for the class in every cycle it has
+	// queued tasks, ahead of the classes before it.
+	Reserved int
+	// Cap bounds the bandwidth of the class in one cycle; 0 is no cap.
+	Cap int
+	// Preemptible classes yield their reservation and their running tasks
+	// to any class before them with queued tasks.
+	Preemptible bool
+}
+
+// ClassStats reports what a service class got since the engine started.
+type ClassStats struct {
+	Name          string
+	ActiveCycles  int // cycles the class had queued tasks
+	HonoredCycles int // active cycles it was offered at least its reservation
+	Allocated     int // bandwidth units its tasks received
+}
+
+// class is a service class with its tenants.
+type class struct {
+	ServiceClass
+	root  *group // 子节点为各租户，自身队列始终为空
+	stats ClassStats
+}
+
+// ParseServiceClasses parses a comma separated list like
+// "critical=2,standard=1,best-effort=0:2:preemptible" of
+// name=reserved[:cap[:preemptible]]; an empty cap is no cap.
+func ParseServiceClasses(s string) ([]ServiceClass, error) {
+	var classes []ServiceClass
+	if strings.TrimSpace(s) == "" {
+		return classes, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		name, spec, ok := strings.Cut(strings.TrimSpace(item), "=")
+		fields := strings.Split(spec, ":")
+		if !ok || name == "" || len(fields) > 3 {
+			return nil, fmt.Errorf("invalid service class %q, expected name=reserved[:cap[:preemptible]]", item)
+		}
+		class := ServiceClass{Name: name}
+		var err error
+		if class.Reserved, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid reservation for class %s: %q", name, fields[0])
+		}
+		if len(fields) > 1 && fields[1] != "" {
+			if class.Cap, err = strconv.Atoi(fields[1]); err != nil {
+				return nil, fmt.Errorf("invalid cap for class %s: %q", name, fields[1])
+			}
+		}
+		if len(fields) > 2 {
+			if fields[2] != "preemptible" {
+				return nil, fmt.Errorf("invalid option for class %s: %q", name, fields[2])
+			}
+			class.Preemptible = true
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+func validateClasses(classes []ServiceClass, defaultClass string, bandwidth int) error {
+	if len(classes) == 0 {
+		return nil
+	}
+	names := make(map[string]bool, len(classes))
+	reserved := 0
+	for _, c := range classes {
+		if c.Name == "" || names[c.Name] {
+			return fmt.Errorf("invalid or duplicate service class name %q", c.Name)
+		}
+		names[c.Name] = true
+		if c.Reserved < 0 || c.Cap < 0 {
+			return fmt.Errorf("class %s cannot have a negative reservation or cap", c.Name)
+		}
+		if c.Cap > 0 && c.Reserved > c.Cap {
+			return fmt.Errorf("class %s reserves %d, more than its cap %d", c.Name, c.Reserved, c.Cap)
+		}
+		reserved += c.Reserved
+	}
+	if reserved > bandwidth {
+		return fmt.Errorf("classes reserve %d units, bandwidth is %d", reserved, bandwidth)
+	}
+	if !names[defaultClass] {
+		return fmt.Errorf("unknown default service class %q", defaultClass)
+	}
+	return nil
+}
+
+// scheduleClasses runs one cycle for every class with queued tasks, in
+// order of importance. Every class is offered what the classes before it
+// left, minus the reservations of the non-preemptible classes after it.
+func (e *Engine) scheduleClasses() []*models.Task {
+	active := make([]bool, len(e.classes))
+	for i, c := range e.classes {
+		active[i] = c.root.queued() > 0
+	}
+
+	var scheduledTasks []*models.Task
+	remaining := e.bandwidth
+	free := e.config.Capacity.Clone()
+	for i, c := range e.classes {
+		if !active[i] {
+			continue
+		}
+		held := 0
+		for j := i + 1; j < len(e.classes); j++ {
+			if active[j] && !e.classes[j].Preemptible {
+				held += e.classes[j].Reserved
+			}
+		}
+		share := max(remaining-held, min(c.Reserved, remaining))
+		if c.Cap > 0 {
+			share = min(share, c.Cap)
+		}
+
+		c.stats.ActiveCycles++
+		if share >= c.Reserved {
+			c.stats.HonoredCycles++
+		}
+		for _, task := range c.root.schedule(share, free.Clone(), e.tick) {
+			remaining -= task.Allocated
+			c.stats.Allocated += task.Allocated
+			free.Sub(task.Resources)
+			scheduledTasks = append(scheduledTasks, task)
+		}
+		remaining = max(remaining, 0)
+	}
+	return scheduledTasks
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestParseServiceClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []ServiceClass
+		wantErr  bool
+	}{
+		{"Empty", "", nil, false},
+		{"Classes", "critical=2,best-effort=0:2:preemptible",
+			[]ServiceClass{{Name: "critical", Reserved: 2}, {Name: "best-effort", Cap: 2, Preemptible: true}}, false},
+		{"Invalid reservation", "critical=x", nil, true},
+		{"Invalid option", "critical=1:2:always", nil, true},
+		{"Empty cap", "best-effort=0::preemptible", []ServiceClass{{Name: "best-effort", Preemptible: true}}, false},
+		{"Too many fields", "critical=1:2:preemptible:x", nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			classes, err := ParseServiceClasses(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if len(classes) != len(tc.expected) {
+				t.Fatalf("Expected %+v, got %+v", tc.expected, classes)
+			}
+			for i := range classes {
+				if classes[i] != tc.expected[i] {
+					t.Errorf("Expected %+v, got %+v", tc.expected[i], classes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewEngine_Classes(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		defaultClass string
+		wantErr      bool
+	}{
+		{"Valid", "critical=2,standard=1", "standard", false},
+		{"Unknown default", "critical=2", "standard", true},
+		{"Over-reserved", "critical=4,standard=2", "standard", true},
+		{"Reservation over cap", "critical=3:2,standard=0", "standard", true},
+		{"Duplicate", "standard=1,standard=1", "standard", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			classes, _ := ParseServiceClasses(tc.input)
+			_, err := NewEngine(EngineOptions{Bandwidth: 5, Classes: classes, DefaultClass: tc.defaultClass})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEngine_Classes(t *testing.T) {
+	tests := []struct {
+		name     string
+		classes  string
+		tasks    map[string]int // 每个类别排队的任务数
+		expected map[string]int // 每个类别本周期分到的带宽
+		honored  map[string]bool
+	}{
+		{"Reservations and caps", "critical=2:3,standard=1,best-effort=1:2:preemptible",
+			map[string]int{"critical": 5, "standard": 5, "best-effort": 5},
+			map[string]int{"critical": 3, "standard": 2, "best-effort": 0},
+			map[string]bool{"critical": true, "standard": true, "best-effort": false}},
+		{"Later reservation held back", "critical=0,standard=2",
+			map[string]int{"critical": 5, "standard": 5},
+			map[string]int{"critical": 3, "standard": 2},
+			map[string]bool{"critical": true, "standard": true}},
+		{"Best-effort alone up to its cap", "critical=2,standard=1,best-effort=1:2:preemptible",
+			map[string]int{"best-effort": 5},
+			map[string]int{"best-effort": 2},
+			map[string]bool{"best-effort": true}},
+		{"Flooding standard cannot starve critical", "critical=2,standard=0",
+			map[string]int{"critical": 1, "standard": 100},
+			map[string]int{"critical": 1, "standard": 4},
+			map[string]bool{"critical": true, "standard": true}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			classes, _ := ParseServiceClasses(tc.classes)
+			engine, err := NewEngine(EngineOptions{Bandwidth: 5, Classes: classes, DefaultClass: classes[0].Name})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for class, count := range tc.tasks {
+				for i := 0; i < count; i++ {
+					task := engine.NewTask(10)
+					task.Class = class
+					task.MaxParallelism = 1
+					engine.ClassGroup(class, "a", "").GetCurrentScheduler().AddTasks(*task)
+				}
+			}
+
+			allocated := make(map[string]int)
+			for _, task := range engine.Schedule() {
+				allocated[task.Class] += task.Allocated
+			}
+			for class, units := range tc.expected {
+				if allocated[class] != units {
+					t.Errorf("Expected class %s to get %d units, got %v", class, units, allocated)
+				}
+			}
+			for _, stats := range engine.ClassStats() {
+				if honored, active := tc.honored[stats.Name]; active && (stats.HonoredCycles == 1) != honored {
+					t.Errorf("Expected the reservation of %s honored %v, got %+v", stats.Name, honored, stats)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"fmt"
+	"scheduler-service/models"
+)
+
+const (
+	SwitchOnResume = "resume" // only a preempted task resuming pays the switch cost
+	SwitchOnChange = "change" // every task joining the running set pays it, including first starts
+)
+
+// FailureModel decides whether a task fails in the cycle it is about to run.
+type FailureModel func(task *models.Task) bool
+
+// EstimateModel revises the total duration estimate of a predicted task
+// from the work it has done so far.
+type EstimateModel func(task *models.Task) int
+
+// Config holds the settings shared by every scheduler registered with a SchedulerManager.
+type Config struct {
+	// Capacity limits the resource dimensions that tasks running in the same cycle may use.
+	Capacity models.Resources
+
+	// Failure, when set, is consulted before every task execution.
+	Failure FailureModel
+
+	// Estimator, when set, revises the estimate of predicted tasks after every execution.
+	Estimator EstimateModel
+
+	// SwitchCost is the bandwidth a task loses in a cycle when it joins the
+	// running set, following SwitchMode.
+	SwitchCost int
+	SwitchMode string
+
+	cycle int
+}
+
+func (c *Config) setContextSwitch(cost int, mode string) error {
+	if cost < 0 {
+		return fmt.Errorf("context switch cost cannot be negative: %d", cost)
+	}
+	if mode == "" {
+		mode = SwitchOnResume
+	}
+	if mode != SwitchOnResume && mode != SwitchOnChange {
+		return fmt.Errorf("unsupported context switch mode: %s", mode)
+	}
+	c.SwitchCost = cost
+	c.SwitchMode = mode
+	return nil
+}
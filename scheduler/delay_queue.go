@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"sort"
+	"time"
+)
+
+// DelayQueue holds tasks until their not-before tick and start time are both reached.
+type DelayQueue struct {
+	byTick *TaskQueue
+	byTime *TaskQueue
+}
+
+func NewDelayQueue() *DelayQueue {
+	return &DelayQueue{
+		byTick: NewTaskQueue(func(a, b *models.Task) bool {
+			if a.NotBefore == b.NotBefore {
+				return a.Index < b.Index
+			}
+			return a.NotBefore < b.NotBefore
+		}),
+		byTime: NewTaskQueue(func(a, b *models.Task) bool {
+			if a.StartAt.Equal(b.StartAt) {
+				return a.Index < b.Index
+			}
+			return a.StartAt.Before(b.StartAt)
+		}),
+	}
+}
+
+// IsDelayed reports whether a task submitted at tick and now must wait in the queue.
+func IsDelayed(task models.Task, tick int, now time.Time) bool {
+	return task.NotBefore > tick || task.StartAt.After(now)
+}
+
+func (q *DelayQueue) Add(task models.Task) {
+	q.byTick.Push(task)
+}
+
+// PopEligible removes and returns the tasks that may run at tick and now.
+func (q *DelayQueue) PopEligible(tick int, now time.Time) []models.Task {
+	// 先按tick释放，仍需等待开始时间的任务转入时间堆
+	for task, ok := q.byTick.Peek(); ok && task.NotBefore <= tick; task, ok = q.byTick.Peek() {
+		q.byTick.Pop()
+		q.byTime.Push(task)
+	}
+
+	var eligible []models.Task
+	for task, ok := q.byTime.Peek(); ok && !task.StartAt.After(now); task, ok = q.byTime.Peek() {
+		q.byTime.Pop()
+		eligible = append(eligible, task)
+	}
+	return eligible
+}
+
+func (q *DelayQueue) Len() int {
+	return q.byTick.Len() + q.byTime.Len()
+}
+
+// Update applies update to the waiting task with the given index. The update
+// must not change NotBefore or StartAt.
+func (q *DelayQueue) Update(index int, update func(task *models.Task)) bool {
+	return q.byTick.Update(index, update) || q.byTime.Update(index, update)
+}
+
+// Remove removes and returns the waiting task with the given index.
+func (q *DelayQueue) Remove(index int) (models.Task, bool) {
+	if task, ok := q.byTick.Remove(index); ok {
+		return task, true
+	}
+	return q.byTime.Remove(index)
+}
+
+// Tasks returns a copy of the waiting tasks ordered by index.
+func (q *DelayQueue) Tasks() []models.Task {
+	tasks := append(q.byTick.Tasks(), q.byTime.Tasks()...)
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Index < tasks[j].Index
+	})
+	return tasks
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+	"time"
+)
+
+func TestDelayQueue_PopEligible(t *testing.T) {
+	now := time.Now()
+	queue := NewDelayQueue()
+	queue.Add(models.Task{Index: 0, NotBefore: 3})
+	queue.Add(models.Task{Index: 1, NotBefore: 1})
+	queue.Add(models.Task{Index: 2, StartAt: now.Add(time.Minute)})
+	queue.Add(models.Task{Index: 3, NotBefore: 2, StartAt: now.Add(time.Minute)})
+
+	if queue.Len() != 4 {
+		t.Fatalf("Expected 4 delayed tasks, got %d", queue.Len())
+	}
+
+	tests := []struct {
+		name            string
+		tick            int
+		now             time.Time
+		expectedIndexes []int
+	}{
+		{"Nothing eligible yet", 0, now, nil},
+		{"Tick reached", 2, now, []int{1}},
+		{"Start time reached", 2, now.Add(time.Minute), []int{2, 3}},
+		{"Remaining tick", 5, now.Add(time.Minute), []int{0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			eligible := queue.PopEligible(tc.tick, tc.now)
+			if len(eligible) != len(tc.expectedIndexes) {
+				t.Fatalf("Expected %d eligible tasks, got %d", len(tc.expectedIndexes), len(eligible))
+			}
+			seen := make(map[int]bool)
+			for _, task := range eligible {
+				seen[task.Index] = true
+			}
+			for _, index := range tc.expectedIndexes {
+				if !seen[index] {
+					t.Errorf("Expected task %d to be eligible", index)
+				}
+			}
+		})
+	}
+
+	if queue.Len() != 0 {
+		t.Errorf("Expected queue to be empty, got %d", queue.Len())
+	}
+}
+
+func TestIsDelayed(t *testing.T) {
+	now := time.Now()
+	if IsDelayed(models.Task{NotBefore: 2}, 2, now) {
+		t.Error("Expected task to be eligible at its not-before tick")
+	}
+	if !IsDelayed(models.Task{NotBefore: 3}, 2, now) {
+		t.Error("Expected task to wait for its not-before tick")
+	}
+	if !IsDelayed(models.Task{StartAt: now.Add(time.Second)}, 0, now) {
+		t.Error("Expected task to wait for its start time")
+	}
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"math"
+	"scheduler-service/models"
+	"sort"
+)
+
+// EASYScheduler runs tasks non-preemptively in FIFO order with EASY backfilling.
+// Each task holds a fixed width from start to completion: its rigid Width,
+// otherwise its MinParallelism or a single unit. When the head of the queue
+// cannot start it gets a reservation at the earliest cycle enough bandwidth
+// frees up, and later tasks may only start now if they do not delay it.
+type EASYScheduler struct {
+	*BaseScheduler
+}
+
+func NewEASYScheduler() *EASYScheduler {
+	baseScheduler := NewBaseScheduler(fifoLess, "EASY")
+	return &EASYScheduler{
+		BaseScheduler: baseScheduler,
+	}
+}
+
+// easyWidth is the bandwidth a task holds for its whole run under EASY.
+func easyWidth(task *models.Task) int {
+	if task.Width > 0 {
+		return task.Width
+	}
+	return max(task.MinParallelism, 1)
+}
+
+// SetPreemptive is a no-op: EASY never preempts a started task.
+func (s *EASYScheduler) SetPreemptive(preemptive bool) {}
+
+func (s *EASYScheduler) IsPreemptive() bool {
+	return false
+}
+
+func (s *EASYScheduler) Schedule(bandwidth int) []*models.Task {
+	s.beginCycle()
+	// 已经开始运行的任务不会被抢占
+	var running, waiting []models.Task
+	for s.queue.Len() > 0 {
+		task, _ := s.queue.Pop()
+		if task.Executions > 0 {
+			running = append(running, task)
+		} else {
+			waiting = append(waiting, task)
+		}
+	}
+
+	freeBandwidth := bandwidth
+	free := s.config.Capacity.Clone()
+	var started []*models.Task
+	fits := func(task *models.Task) bool {
+		return easyWidth(task) <= freeBandwidth && task.Resources.Fits(free)
+	}
+	start := func(task *models.Task) {
+		freeBandwidth -= easyWidth(task)
+		free.Sub(task.Resources)
+		started = append(started, task)
+	}
+
+	for i := range running {
+		if fits(&running[i]) {
+			start(&running[i])
+		}
+	}
+
+	head := 0
+	for head < len(waiting) && fits(&waiting[head]) {
+		start(&waiting[head])
+		head++
+	}
+
+	// 宽度超出本组份额的任务等不到其他任务结束，需要上层借出带宽
+	s.reservation = nil
+	blocked := waiting[head:min(head+1, len(waiting))]
+	for _, task := range append(running[:len(running):len(running)], blocked...) {
+		if width := easyWidth(&task); width > bandwidth {
+			s.reservation = &reservation{index: task.Index, width: width}
+			break
+		}
+	}
+	if head < len(waiting) {
+		shadow, extra := s.reserve(&waiting[head], started, freeBandwidth)
+		for i := head + 1; i < len(waiting); i++ {
+			task := &waiting[i]
+			if !fits(task) {
+				continue
+			}
+			width := easyWidth(task)
+			if task.CyclesAt(width) <= shadow {
+				start(task)
+			} else if width <= extra {
+				extra -= width
+				start(task)
+			}
+		}
+	}
+
+	var scheduledTasks []*models.Task
+	for _, task := range started {
+		s.run(task, easyWidth(task))
+		scheduled := *task
+		scheduledTasks = append(scheduledTasks, &scheduled)
+	}
+
+	for _, tasks := range [][]models.Task{running, waiting} {
+		for _, task := range tasks {
+			if !task.IsFinished() {
+				s.queue.Push(task)
+			}
+		}
+	}
+
+	return scheduledTasks
+}
+
+// reserve returns the shadow time, in cycles from now, at which head can
+// start once running tasks finish, and the bandwidth left over at that time.
+func (s *EASYScheduler) reserve(head *models.Task, running []*models.Task, freeBandwidth int) (int, int) {
+	ends := make([]*models.Task, len(running))
+	copy(ends, running)
+	sort.Slice(ends, func(i, j int) bool {
+		return ends[i].CyclesAt(easyWidth(ends[i])) < ends[j].CyclesAt(easyWidth(ends[j]))
+	})
+
+	width := easyWidth(head)
+	for _, task := range ends {
+		freeBandwidth += easyWidth(task)
+		if freeBandwidth >= width {
+			return task.CyclesAt(easyWidth(task)), freeBandwidth - width
+		}
+	}
+	return math.MaxInt, 0
+}
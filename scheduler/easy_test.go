@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+	"time"
+)
+
+func TestEASYScheduler_GetName(t *testing.T) {
+	easy := NewEASYScheduler()
+	if easy.GetName() != "EASY" {
+		t.Errorf("Expected scheduler name to be EASY, got %s", easy.GetName())
+	}
+}
+
+func TestEASYScheduler_Backfill(t *testing.T) {
+	now := time.Now()
+	easy := NewEASYScheduler()
+	easy.AddTasks(models.Task{Index: 0, RemainingTime: 4, Width: 2, CreatedTime: now})
+	easy.AddTasks(models.Task{Index: 1, RemainingTime: 4, Width: 4, CreatedTime: now.Add(time.Millisecond)})
+	easy.AddTasks(models.Task{Index: 2, RemainingTime: 1, CreatedTime: now.Add(2 * time.Millisecond)})
+	easy.AddTasks(models.Task{Index: 3, RemainingTime: 10, CreatedTime: now.Add(3 * time.Millisecond)})
+
+	cycles := [][]int{
+		{0, 2}, // 任务2在预留时间前完成，可以回填；任务3会推迟任务1，不能回填
+		{0},
+		{1},
+		{3},
+	}
+
+	for cycle, expectedIndexes := range cycles {
+		scheduledTasks := easy.Schedule(4)
+		if len(scheduledTasks) != len(expectedIndexes) {
+			t.Fatalf("Cycle %d: expected %d scheduled tasks, got %d",
+				cycle, len(expectedIndexes), len(scheduledTasks))
+		}
+		for i, task := range scheduledTasks {
+			if task.Index != expectedIndexes[i] {
+				t.Errorf("Cycle %d: expected task index %d, got %d",
+					cycle, expectedIndexes[i], task.Index)
+			}
+		}
+	}
+}
+
+func TestEASYScheduler_NonPreemptive(t *testing.T) {
+	now := time.Now()
+	easy := NewEASYScheduler()
+	easy.AddTasks(models.Task{Index: 0, RemainingTime: 6, Width: 3, CreatedTime: now})
+
+	easy.Schedule(3)
+
+	// 更早提交的任务也不能抢占已经开始的任务
+	easy.AddTasks(models.Task{Index: 1, RemainingTime: 1, Width: 3, CreatedTime: now.Add(-time.Second)})
+
+	scheduledTasks := easy.Schedule(3)
+	if len(scheduledTasks) != 1 || scheduledTasks[0].Index != 0 {
+		t.Fatalf("Expected running task 0 to keep its bandwidth, got %v", scheduledTasks)
+	}
+}
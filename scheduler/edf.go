@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+)
+
+// EDFScheduler runs the task with the earliest deadline first. Tasks
+// without a deadline run after all tasks that have one.
+type EDFScheduler struct {
+	*BaseScheduler
+}
+
+func NewEDFScheduler() *EDFScheduler {
+	baseScheduler := NewBaseScheduler(edfLess, "EDF")
+	return &EDFScheduler{
+		BaseScheduler: baseScheduler,
+	}
+}
+
+func edfLess(a, b *models.Task) bool {
+	if less, ok := byPriority(a, b); ok {
+		return less
+	}
+	if a.Deadline != b.Deadline {
+		// 没有截止时间的任务排在最后
+		return b.Deadline == 0 || (a.Deadline != 0 && a.Deadline < b.Deadline)
+	}
+	return a.Index < b.Index
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+)
+
+func TestEDFScheduler_GetName(t *testing.T) {
+	edf := NewEDFScheduler()
+	if edf.GetName() != "EDF" {
+		t.Errorf("Expected scheduler name to be EDF, got %s", edf.GetName())
+	}
+}
+
+func TestEDFScheduler_Schedule(t *testing.T) {
+	tests := []struct {
+		name            string
+		tasks           []models.Task
+		expectedIndexes []int
+	}{
+		{
+			name: "Earliest deadline first",
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 2, Deadline: 9},
+				{Index: 1, RemainingTime: 2, Deadline: 4},
+				{Index: 2, RemainingTime: 2, Deadline: 6},
+			},
+			expectedIndexes: []int{1, 2, 0},
+		},
+		{
+			name: "Tasks without deadline run last",
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 2},
+				{Index: 1, RemainingTime: 2, Deadline: 20},
+				{Index: 2, RemainingTime: 2},
+			},
+			expectedIndexes: []int{1, 0, 2},
+		},
+		{
+			name: "Priority before deadline",
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 2, Deadline: 3},
+				{Index: 1, RemainingTime: 2, Deadline: 8, Priority: 1},
+			},
+			expectedIndexes: []int{1, 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			edf := NewEDFScheduler()
+			for _, task := range tc.tasks {
+				edf.AddTasks(task)
+			}
+
+			scheduledTasks := edf.Schedule(6)
+			if len(scheduledTasks) != len(tc.expectedIndexes) {
+				t.Fatalf("Expected %d scheduled tasks, got %d", len(tc.expectedIndexes), len(scheduledTasks))
+			}
+			for i, task := range scheduledTasks {
+				if task.Index != tc.expectedIndexes[i] {
+					t.Errorf("Expected task index %d at position %d, got %d", tc.expectedIndexes[i], i, task.Index)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,553 @@
+package scheduler
+
+import (
+	"fmt"
+	"scheduler-service/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How an engine divides the bandwidth of a cycle between tenants with
+// queued tasks, and a group between its own queue and its subgroups.
+const (
+	TenantFair     = "fair"     // shares in proportion to weights
+	TenantPriority = "priority" // heavier first, the rest get what is left
+)
+
+// Clock returns the wall time an engine uses for task creation times and
+// start_at delays.
+type Clock func() time.Time
+
+// EngineOptions configures a new Engine.
+type EngineOptions struct {
+	// Bandwidth is the number of units shared by the tasks of one cycle.
+	Bandwidth int
+
+	// Strategies limits the strategies the engine offers, starting with the
+	// first one. Empty offers every strategy, starting with FIFO.
+	Strategies []string
+
+	// TenantPolicy is TenantFair (default) or TenantPriority.
+	TenantPolicy string
+
+	// Partitions split the share of every tenant between strategies running
+	// side by side. Empty queues all tasks of a tenant under one strategy.
+	Partitions []Partition
+
+	// Classes are the service classes, most important first. Empty puts
+	// every task in one class without reservation.
+	Classes []ServiceClass
+
+	// DefaultClass takes the tasks submitted without a class.
+	DefaultClass string
+
+	// Burst gives every tenant burst credits; the zero value gives none.
+	Burst TokenBucket
+
+	// Clock defaults to time.Now.
+	Clock Clock
+}
+
+// Engine is one isolated scheduling engine: it owns its task indexes, its
+// cycle clock, its bandwidth and its set of strategies. Engines share no
+// state, so one process can run many of them side by side. Apart from
+// NewTask, an Engine is not safe for concurrent use.
+//
+// Every tenant of an engine is the root of a tree of groups, each with its
+// own queue and current strategy. The tenant policy divides the bandwidth
+// between the tenants, and every group's policy divides its share further
+// down the tree. With service classes, every class has such a tree of its
+// own and gets its share of the bandwidth first.
+type Engine struct {
+	ids          models.IDAllocator
+	bandwidth    int
+	tick         int
+	clock        Clock
+	strategies   []string
+	partitions   []Partition
+	classes      []*class // 按重要程度排序，未配置时只有一个无名类别
+	defaultClass *class
+	burst        TokenBucket
+	buckets      map[string]*bucket // 各租户的突发额度
+	config       Config             // 所有租户共享的设置
+}
+
+func NewEngine(opts EngineOptions) (*Engine, error) {
+	if opts.Bandwidth <= 0 {
+		return nil, fmt.Errorf("bandwidth must be positive: %d", opts.Bandwidth)
+	}
+	manager, err := NewSchedulerManagerFor(opts.Strategies)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePartitions(opts.Partitions, manager); err != nil {
+		return nil, err
+	}
+	if opts.TenantPolicy == "" {
+		opts.TenantPolicy = TenantFair
+	}
+	if opts.TenantPolicy != TenantFair && opts.TenantPolicy != TenantPriority {
+		return nil, fmt.Errorf("unsupported tenant policy: %s", opts.TenantPolicy)
+	}
+	if err := validateClasses(opts.Classes, opts.DefaultClass, opts.Bandwidth); err != nil {
+		return nil, err
+	}
+	if len(opts.Classes) == 0 {
+		opts.Classes = []ServiceClass{{}}
+	}
+	if err := opts.Burst.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+	e := &Engine{
+		bandwidth:  opts.Bandwidth,
+		clock:      opts.Clock,
+		strategies: opts.Strategies,
+		partitions: opts.Partitions,
+		burst:      opts.Burst,
+		buckets:    make(map[string]*bucket),
+	}
+	for _, sc := range opts.Classes {
+		c := &class{ServiceClass: sc, root: e.newGroup(), stats: ClassStats{Name: sc.Name}}
+		c.root.policy = opts.TenantPolicy
+		e.classes = append(e.classes, c)
+		if sc.Name == opts.DefaultClass {
+			e.defaultClass = c
+		}
+	}
+	if e.defaultClass == nil {
+		e.defaultClass = e.classes[0]
+	}
+	return e, nil
+}
+
+// NewTask creates a task with the next index of this engine.
+func (e *Engine) NewTask(duration int) *models.Task {
+	task := models.NewTask(duration)
+	task.Index = e.ids.Next()
+	task.CreatedTime = e.clock()
+	return task
+}
+
+func (e *Engine) Bandwidth() int {
+	return e.bandwidth
+}
+
+// SetBandwidth changes the units of every following cycle. It is refused
+// if the service classes reserve more, or if a queued task needs more to
+// run at all.
+func (e *Engine) SetBandwidth(bandwidth int) error {
+	if bandwidth <= 0 {
+		return fmt.Errorf("bandwidth must be positive: %d", bandwidth)
+	}
+	if len(e.classes) > 1 || e.classes[0].Name != "" {
+		configs := make([]ServiceClass, len(e.classes))
+		for i, c := range e.classes {
+			configs[i] = c.ServiceClass
+		}
+		if err := validateClasses(configs, e.defaultClass.Name, bandwidth); err != nil {
+			return err
+		}
+	}
+	for _, c := range e.classes {
+		var err error
+		c.root.walk("", func(path string, g *group) {
+			for _, task := range g.manager.GetCurrentScheduler().Tasks() {
+				if need := max(task.Width, task.MinParallelism); err == nil && need > bandwidth {
+					err = fmt.Errorf("queued task %d needs %d units, more than bandwidth %d", task.Index, need, bandwidth)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	e.bandwidth = bandwidth
+	return nil
+}
+
+// CurrentTime returns the current scheduling cycle.
+func (e *Engine) CurrentTime() int {
+	return e.tick
+}
+
+// Now returns the wall time of the engine's clock.
+func (e *Engine) Now() time.Time {
+	return e.clock()
+}
+
+// SetClock replaces the wall clock, e.g. with a simulated one.
+func (e *Engine) SetClock(clock Clock) {
+	e.clock = clock
+}
+
+func (e *Engine) newGroup() *group {
+	manager, _ := NewSchedulerManagerFor(e.strategies)
+	*manager.config = e.config
+	return &group{manager: manager, weight: 1, policy: TenantFair, children: make(map[string]*group)}
+}
+
+// Tenant returns the queue and strategies of the named tenant in the
+// default class, creating them on first use.
+func (e *Engine) Tenant(name string) *SchedulerManager {
+	return e.group(e.defaultClass, name, nil).manager
+}
+
+// Group returns the queue and strategies of the group at path below the
+// tenant in the default class, creating the groups on the way on first use.
+// path must be valid, see ParseGroupPath.
+func (e *Engine) Group(tenant, path string) *SchedulerManager {
+	return e.ClassGroup("", tenant, path)
+}
+
+// ClassGroup is Group within the named service class; the empty name and
+// unknown names stand for the default class, see Class.
+func (e *Engine) ClassGroup(class, tenant, path string) *SchedulerManager {
+	names, _ := ParseGroupPath(path)
+	return e.group(e.class(class), tenant, names).manager
+}
+
+// Class returns the name of the service class a task asking for name
+// belongs to; the empty name is the default class.
+func (e *Engine) Class(name string) (string, error) {
+	if name == "" {
+		return e.defaultClass.Name, nil
+	}
+	for _, c := range e.classes {
+		if c.Name == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown service class: %s", name)
+}
+
+func (e *Engine) class(name string) *class {
+	for _, c := range e.classes {
+		if c.Name == name {
+			return c
+		}
+	}
+	return e.defaultClass
+}
+
+// group returns the group at path below the tenant in class c. A new
+// tenant is created in every class at once.
+func (e *Engine) group(c *class, tenant string, path []string) *group {
+	if _, exists := c.root.children[tenant]; !exists {
+		for _, other := range e.classes {
+			e.addPartitions(e.newChild(other.root, tenant))
+		}
+		if e.burst != (TokenBucket{}) {
+			e.setBucket(tenant, newBucket(e.burst))
+		}
+	}
+	g := c.root.children[tenant]
+	for _, name := range path {
+		child, exists := g.children[name]
+		if !exists {
+			child = e.newChild(g, name)
+		}
+		g = child
+	}
+	return g
+}
+
+func (e *Engine) newChild(parent *group, name string) *group {
+	child := e.newGroup()
+	parent.children[name] = child
+	i, _ := slices.BinarySearch(parent.names, name)
+	parent.names = slices.Insert(parent.names, i, name)
+	return child
+}
+
+// addPartitions gives a new tenant a group per partition.
+func (e *Engine) addPartitions(tenant *group) {
+	for _, p := range e.partitions {
+		child := e.newChild(tenant, p.Name)
+		child.weight = p.Bandwidth
+		child.manager.SwitchScheduler(p.Strategy)
+	}
+}
+
+// Partitioned reports whether tenants are split into partitions.
+func (e *Engine) Partitioned() bool {
+	return len(e.partitions) > 0
+}
+
+// Route returns the group path a task with labels submitted to group goes
+// to: group itself, or group within the partition matching the labels.
+func (e *Engine) Route(labels map[string]string, group string) string {
+	if len(e.partitions) == 0 {
+		return group
+	}
+	partition := ""
+	for _, p := range e.partitions {
+		if len(p.Match) == 0 {
+			partition = p.Name // 没有其他分区匹配时的去处
+		} else if p.matches(labels) {
+			partition = p.Name
+			break
+		}
+	}
+	if group == "" {
+		return partition
+	}
+	return partition + "/" + group
+}
+
+// Tenants returns the names of the tenants in name order.
+func (e *Engine) Tenants() []string {
+	return slices.Clone(e.defaultClass.root.names)
+}
+
+// Share estimates the units tenant gets per cycle: its weighted part of the
+// bandwidth among the tenants with queued tasks, counting tenant as one of
+// them. Under TenantPriority a lighter tenant may get less.
+func (e *Engine) Share(tenant string) int {
+	root := e.defaultClass.root
+	weight := 1
+	if t, exists := root.children[tenant]; exists {
+		weight = t.weight
+	}
+	total := weight
+	for _, name := range root.names {
+		if name == tenant {
+			continue
+		}
+		for _, c := range e.classes {
+			if t := c.root.children[name]; t != nil && t.queued() > 0 {
+				total += root.children[name].weight
+				break
+			}
+		}
+	}
+	return max(e.bandwidth*weight/total, 1)
+}
+
+// Groups returns the tenant's groups class by class, each depth first in
+// name order and starting with the tenant itself.
+func (e *Engine) Groups(tenant string) []GroupInfo {
+	var groups []GroupInfo
+	for _, c := range e.classes {
+		t, exists := c.root.children[tenant]
+		if !exists {
+			return nil
+		}
+		t.walk("", func(path string, g *group) {
+			groups = append(groups, GroupInfo{Class: c.Name, Path: path, Weight: g.weight, Policy: g.policy, Manager: g.manager})
+		})
+	}
+	return groups
+}
+
+// SetTenantBurst replaces the burst credits of the named tenant, starting
+// with a full bucket; the zero value takes them away.
+func (e *Engine) SetTenantBurst(name string, config TokenBucket) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	e.Tenant(name)
+	if config == (TokenBucket{}) {
+		e.setBucket(name, nil)
+		return nil
+	}
+	e.setBucket(name, newBucket(config))
+	return nil
+}
+
+func (e *Engine) setBucket(tenant string, b *bucket) {
+	if b == nil {
+		delete(e.buckets, tenant)
+	} else {
+		e.buckets[tenant] = b
+	}
+	for _, c := range e.classes {
+		c.root.children[tenant].bucket = b
+	}
+}
+
+// Credits returns the burst credits of the tenant and the bandwidth units
+// it used above its fair share so far; ok is false without burst credits.
+func (e *Engine) Credits(tenant string) (credits float64, burst int, ok bool) {
+	b, exists := e.buckets[tenant]
+	if !exists {
+		return 0, 0, false
+	}
+	return b.credits, b.burst, true
+}
+
+// BurstCredits returns the credits left of every tenant with burst credits,
+// nil without any, and the bandwidth they got above their shares at the
+// last cycle.
+func (e *Engine) BurstCredits() (map[string]float64, int) {
+	if len(e.buckets) == 0 {
+		return nil, 0
+	}
+	credits := make(map[string]float64, len(e.buckets))
+	total := 0
+	for tenant, b := range e.buckets {
+		credits[tenant] = b.credits
+		total += b.burst
+	}
+	return credits, total
+}
+
+// GroupManagers returns the queues and strategies of the group at path in
+// every class, creating them on first use.
+func (e *Engine) GroupManagers(tenant, path string) []*SchedulerManager {
+	names, _ := ParseGroupPath(path)
+	managers := make([]*SchedulerManager, len(e.classes))
+	for i, c := range e.classes {
+		managers[i] = e.group(c, tenant, names).manager
+	}
+	return managers
+}
+
+// ClassStats returns what every service class got so far, most important
+// class first; nil without service classes.
+func (e *Engine) ClassStats() []ClassStats {
+	if len(e.classes) == 1 && e.classes[0].Name == "" {
+		return nil
+	}
+	stats := make([]ClassStats, len(e.classes))
+	for i, c := range e.classes {
+		stats[i] = c.stats
+	}
+	return stats
+}
+
+// SetTenantWeight sets the weight the tenant policy gives the named tenant; the default is 1.
+func (e *Engine) SetTenantWeight(name string, weight int) error {
+	if weight <= 0 {
+		return fmt.Errorf("tenant weight must be positive: %d", weight)
+	}
+	return e.SetGroup(name, "", weight, "")
+}
+
+// SetGroup creates or changes the group at path below the tenant: its
+// weight within its parent, 1 for new groups, and the policy dividing its
+// share between its own queue and its subgroups, TenantFair for new groups.
+// A zero weight or an empty policy keeps the current one.
+func (e *Engine) SetGroup(tenant, path string, weight int, policy string) error {
+	names, err := ParseGroupPath(path)
+	if err != nil {
+		return err
+	}
+	if weight < 0 {
+		return fmt.Errorf("group weight cannot be negative: %d", weight)
+	}
+	if policy != "" && policy != TenantFair && policy != TenantPriority {
+		return fmt.Errorf("unsupported group policy: %s", policy)
+	}
+	for _, c := range e.classes {
+		g := e.group(c, tenant, names)
+		if weight > 0 {
+			g.weight = weight
+		}
+		if policy != "" {
+			g.policy = policy
+		}
+	}
+	return nil
+}
+
+// GetAvailableStrategies returns the strategies every tenant can choose from.
+func (e *Engine) GetAvailableStrategies() []string {
+	manager, _ := NewSchedulerManagerFor(e.strategies)
+	return manager.GetAvailableStrategies()
+}
+
+// DefaultStrategy returns the strategy a new tenant starts with.
+func (e *Engine) DefaultStrategy() string {
+	if len(e.strategies) == 0 {
+		return "FIFO"
+	}
+	return e.strategies[0]
+}
+
+// GetTasksLen returns the number of queued tasks of all tenants.
+func (e *Engine) GetTasksLen() int {
+	total := 0
+	for _, c := range e.classes {
+		total += c.root.queued()
+	}
+	return total
+}
+
+// SetCapacity sets the resource capacity shared by the tasks of all tenants in one cycle.
+func (e *Engine) SetCapacity(capacity models.Resources) {
+	e.config.Capacity = capacity.Clone()
+	e.syncConfig()
+}
+
+// SetFailureModel sets the model used to simulate task failures.
+func (e *Engine) SetFailureModel(failure FailureModel) {
+	e.config.Failure = failure
+	e.syncConfig()
+}
+
+// SetEstimateModel sets the model used to revise predicted estimates while tasks run.
+func (e *Engine) SetEstimateModel(estimator EstimateModel) {
+	e.config.Estimator = estimator
+	e.syncConfig()
+}
+
+// SetContextSwitch sets the bandwidth a task loses when it joins the running set.
+func (e *Engine) SetContextSwitch(cost int, mode string) error {
+	if err := e.config.setContextSwitch(cost, mode); err != nil {
+		return err
+	}
+	e.syncConfig()
+	return nil
+}
+
+// syncConfig copies the shared settings to every group.
+func (e *Engine) syncConfig() {
+	for _, c := range e.classes {
+		c.root.walk("", func(_ string, g *group) {
+			*g.manager.config = e.config
+		})
+	}
+}
+
+// Schedule runs one cycle for every tenant with queued tasks. The service
+// classes take their shares first; within a class the tenant policy decides
+// the order and share of each tenant, and the policies of the groups below
+// hand it down to their queues. Bandwidth and resource capacity one leaves
+// unused pass on to the next. Advance moves on to the next cycle.
+func (e *Engine) Schedule() []*models.Task {
+	for _, b := range e.buckets {
+		b.refill()
+	}
+	return e.scheduleClasses()
+}
+
+// Advance moves the clock to the next cycle.
+func (e *Engine) Advance() {
+	e.tick++
+}
+
+// ParseTenantWeights parses a comma separated list like "team-a=3,team-b=1".
+func ParseTenantWeights(s string) (map[string]int, error) {
+	weights := make(map[string]int)
+	if strings.TrimSpace(s) == "" {
+		return weights, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid tenant weight %q, expected tenant=weight", pair)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid weight for tenant %s: %q", name, value)
+		}
+		weights[name] = weight
+	}
+	return weights, nil
+}
@@ -0,0 +1,339 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    EngineOptions
+		wantErr bool
+	}{
+		{"Defaults", EngineOptions{Bandwidth: 5}, false},
+		{"Strategy subset", EngineOptions{Bandwidth: 5, Strategies: []string{"SRTF", "FIFO"}}, false},
+		{"Unknown strategy", EngineOptions{Bandwidth: 5, Strategies: []string{"LIFO"}}, true},
+		{"No bandwidth", EngineOptions{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	engine, _ := NewEngine(EngineOptions{Bandwidth: 5, Strategies: []string{"SRTF", "FIFO"}})
+	tenant := engine.Tenant(models.DefaultTenant)
+	if tenant.GetCurrentScheduler().GetName() != "SRTF" {
+		t.Errorf("Expected the first strategy to be current, got %s", tenant.GetCurrentScheduler().GetName())
+	}
+	if err := tenant.SwitchScheduler("EDF"); err == nil {
+		t.Error("Expected a strategy outside the engine's set to be rejected")
+	}
+}
+
+func TestEngine_Isolation(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engines := make([]*Engine, 8)
+	for i := range engines {
+		engines[i], _ = NewEngine(EngineOptions{Bandwidth: 2, Clock: func() time.Time { return created }})
+	}
+
+	// 各引擎并行运行，任务编号互不干扰
+	var wg sync.WaitGroup
+	for _, engine := range engines {
+		wg.Add(1)
+		go func(engine *Engine) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				engine.Tenant(models.DefaultTenant).GetCurrentScheduler().AddTasks(*engine.NewTask(1))
+			}
+			for engine.GetTasksLen() > 0 {
+				engine.Schedule()
+				engine.Advance()
+			}
+		}(engine)
+	}
+	wg.Wait()
+
+	for i, engine := range engines {
+		if engine.CurrentTime() != 50 {
+			t.Errorf("Engine %d: expected 50 cycles, got %d", i, engine.CurrentTime())
+		}
+		task := engine.NewTask(1)
+		if task.Index != 100 || !task.CreatedTime.Equal(created) {
+			t.Errorf("Engine %d: expected index 100 created at the engine's clock, got %d at %v",
+				i, task.Index, task.CreatedTime)
+		}
+	}
+}
+
+func TestEngine_TenantShares(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		weights  map[string]int
+		tasks    map[string][]int // 每个租户排队任务的时长
+		expected map[string]int   // 每个租户本周期分到的带宽
+	}{
+		{"Equal weights", TenantFair, nil,
+			map[string][]int{"a": {10, 10}, "b": {10, 10}}, map[string]int{"a": 2, "b": 2}},
+		{"Weighted", TenantFair, map[string]int{"a": 3},
+			map[string][]int{"a": {10, 10, 10}, "b": {10, 10, 10}}, map[string]int{"a": 3, "b": 1}},
+		{"Unused share passes on", TenantFair, nil,
+			map[string][]int{"a": {1}, "b": {10, 10, 10}}, map[string]int{"a": 1, "b": 3}},
+		{"Idle tenant gets nothing", TenantFair, map[string]int{"idle": 5},
+			map[string][]int{"idle": {}, "b": {10, 10, 10, 10}}, map[string]int{"b": 4}},
+		{"Priority", TenantPriority, map[string]int{"b": 2},
+			map[string][]int{"a": {10, 10}, "b": {10, 10, 10}}, map[string]int{"a": 1, "b": 3}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, _ := NewEngine(EngineOptions{Bandwidth: 4, TenantPolicy: tc.policy})
+			for name, weight := range tc.weights {
+				engine.SetTenantWeight(name, weight)
+			}
+			for name, durations := range tc.tasks {
+				for _, duration := range durations {
+					task := engine.NewTask(duration)
+					task.Tenant = name
+					task.MaxParallelism = 1
+					engine.Tenant(name).GetCurrentScheduler().AddTasks(*task)
+				}
+			}
+
+			allocated := make(map[string]int)
+			for _, task := range engine.Schedule() {
+				allocated[task.Tenant] += task.Allocated
+			}
+			for name := range tc.tasks {
+				if allocated[name] != tc.expected[name] {
+					t.Errorf("Expected tenant %s to get %d units, got %v", name, tc.expected[name], allocated)
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_NeverExceedsBandwidth(t *testing.T) {
+	for strategy := range strategies {
+		t.Run(strategy, func(t *testing.T) {
+			engine, err := NewEngine(EngineOptions{Bandwidth: 10, Strategies: []string{strategy}, TenantPolicy: TenantPriority})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// 租户b先分到5个单位，租户a只剩5个单位，宽度8的预留不能超出份额运行
+			engine.SetTenantWeight("b", 2)
+			wide := engine.NewTask(80)
+			wide.Tenant = "a"
+			wide.Width = 8
+			engine.Tenant("a").GetCurrentScheduler().AddTasks(*wide)
+			long := engine.NewTask(1000)
+			long.Tenant = "b"
+			long.MaxParallelism = 5
+			engine.Tenant("b").GetCurrentScheduler().AddTasks(*long)
+
+			for cycle := 0; cycle < 20; cycle++ {
+				total := 0
+				for _, task := range engine.Schedule() {
+					total += task.Allocated
+				}
+				if total > engine.Bandwidth() {
+					t.Fatalf("Cycle %d allocated %d units, bandwidth is %d", cycle, total, engine.Bandwidth())
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_SetBandwidth(t *testing.T) {
+	classes, _ := ParseServiceClasses("critical=3,standard=0")
+	engine, err := NewEngine(EngineOptions{Bandwidth: 5, Classes: classes, DefaultClass: "standard"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wide := engine.NewTask(10)
+	wide.Width = 4
+	engine.Tenant("a").GetCurrentScheduler().AddTasks(*wide)
+
+	tests := []struct {
+		name      string
+		bandwidth int
+		wantErr   bool
+	}{
+		{"Zero", 0, true},
+		{"Below the reservations", 2, true},
+		{"Below a queued width", 3, true},
+		{"Raised", 8, false},
+		{"Lowered", 4, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := engine.Bandwidth()
+			err := engine.SetBandwidth(tc.bandwidth)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tc.wantErr, err)
+			}
+			expected := tc.bandwidth
+			if tc.wantErr {
+				expected = previous
+			}
+			if engine.Bandwidth() != expected {
+				t.Errorf("Expected bandwidth %d, got %d", expected, engine.Bandwidth())
+			}
+		})
+	}
+}
+
+func TestEngine_WideTaskProgress(t *testing.T) {
+	for strategy := range strategies {
+		for _, policy := range []string{TenantFair, TenantPriority} {
+			t.Run(strategy+"/"+policy, func(t *testing.T) {
+				engine, err := NewEngine(EngineOptions{Bandwidth: 10, Strategies: []string{strategy}, TenantPolicy: policy})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				// a的份额小于宽度8，受阻后向b借用带宽
+				engine.SetTenantWeight("b", 2)
+				wide := engine.NewTask(16)
+				wide.Tenant = "a"
+				wide.Width = 8
+				engine.Tenant("a").GetCurrentScheduler().AddTasks(*wide)
+				long := engine.NewTask(1000)
+				long.Tenant = "b"
+				long.MaxParallelism = 5
+				engine.Tenant("b").GetCurrentScheduler().AddTasks(*long)
+
+				for cycle := 0; cycle < 10; cycle++ {
+					total := 0
+					for _, task := range engine.Schedule() {
+						total += task.Allocated
+						if task.Index == wide.Index && task.IsCompleted {
+							return
+						}
+					}
+					if total > engine.Bandwidth() {
+						t.Fatalf("Cycle %d allocated %d units, bandwidth is %d", cycle, total, engine.Bandwidth())
+					}
+					engine.Advance()
+				}
+				t.Error("Expected the wide task to complete within 10 cycles")
+			})
+		}
+	}
+}
+
+func TestEngine_TenantStrategies(t *testing.T) {
+	engine, _ := NewEngine(EngineOptions{Bandwidth: 4})
+	if err := engine.Tenant("a").SwitchScheduler("SRTF"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name := engine.Tenant("b").GetCurrentScheduler().GetName(); name != "FIFO" {
+		t.Errorf("Expected another tenant to keep FIFO, got %s", name)
+	}
+	if tenants := engine.Tenants(); len(tenants) != 2 || tenants[0] != "a" || tenants[1] != "b" {
+		t.Errorf("Expected tenants [a b], got %v", tenants)
+	}
+	if err := engine.SetTenantWeight("a", 0); err == nil {
+		t.Error("Expected a zero weight to be rejected")
+	}
+}
+
+func TestEngine_Groups(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   map[string]int   // 组路径及其权重
+		policy   string           // org 组的分配方式
+		tasks    map[string][]int // 每个组排队任务的时长
+		expected map[string]int   // 每个组本周期分到的带宽
+	}{
+		{"Weighted teams", map[string]int{"eng": 3, "ops": 1}, TenantFair,
+			map[string][]int{"eng": {10, 10, 10, 10, 10, 10}, "ops": {10, 10, 10}}, map[string]int{"eng": 6, "ops": 2}},
+		{"Own queue weighs 1", map[string]int{"eng": 3}, TenantFair,
+			map[string][]int{"": {10, 10, 10}, "eng": {10, 10, 10, 10, 10, 10, 10, 10}}, map[string]int{"": 2, "eng": 6}},
+		{"Nested groups", map[string]int{"eng": 2, "eng/search": 1, "eng/ads": 1, "ops": 2}, TenantFair,
+			map[string][]int{"eng/search": {10, 10, 10}, "eng/ads": {10, 10, 10}, "ops": {10, 10, 10, 10}},
+			map[string]int{"eng/search": 2, "eng/ads": 2, "ops": 4}},
+		{"Priority", map[string]int{"eng": 3, "ops": 1}, TenantPriority,
+			map[string][]int{"eng": {10, 10, 10, 10, 10}, "ops": {10, 10, 10, 10}}, map[string]int{"eng": 5, "ops": 3}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, _ := NewEngine(EngineOptions{Bandwidth: 8})
+			if err := engine.SetGroup("org", "", 1, tc.policy); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for path, weight := range tc.groups {
+				if err := engine.SetGroup("org", path, weight, ""); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			for path, durations := range tc.tasks {
+				for _, duration := range durations {
+					task := engine.NewTask(duration)
+					task.Group = path
+					task.MaxParallelism = 1
+					engine.Group("org", path).GetCurrentScheduler().AddTasks(*task)
+				}
+			}
+
+			allocated := make(map[string]int)
+			for _, task := range engine.Schedule() {
+				allocated[task.Group] += task.Allocated
+			}
+			for path := range tc.tasks {
+				if allocated[path] != tc.expected[path] {
+					t.Errorf("Expected group %q to get %d units, got %v", path, tc.expected[path], allocated)
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_GroupStrategies(t *testing.T) {
+	engine, _ := NewEngine(EngineOptions{Bandwidth: 1})
+	if err := engine.Group("org", "ops").SwitchScheduler("SRTF"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, path := range []string{"eng", "ops"} {
+		for _, duration := range []int{5, 2} {
+			task := engine.NewTask(duration)
+			task.Group = path
+			engine.Group("org", path).GetCurrentScheduler().AddTasks(*task)
+		}
+	}
+
+	// 各组按自己的策略选择任务：eng 先到先服务，ops 最短剩余时间优先
+	first := make(map[string]int)
+	for engine.GetTasksLen() > 0 {
+		for _, task := range engine.Schedule() {
+			if _, exists := first[task.Group]; !exists {
+				first[task.Group] = task.Duration
+			}
+		}
+		engine.Advance()
+	}
+	if first["eng"] != 5 || first["ops"] != 2 {
+		t.Errorf("Expected eng to start with 5 and ops with 2, got %v", first)
+	}
+
+	groups := engine.Groups("org")
+	if len(groups) != 3 || groups[1].Path != "eng" || groups[2].Path != "ops" {
+		t.Errorf("Expected groups org, eng and ops, got %+v", groups)
+	}
+	if _, err := ParseGroupPath("eng//search"); err == nil {
+		t.Error("Expected an empty group name to be rejected")
+	}
+	if err := engine.SetGroup("org", "eng", 1, "random"); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
@@ -94,3 +94,21 @@ func TestFIFOScheduler_Schedule(t *testing.T) {
 		})
 	}
 }
+
+func TestFIFOScheduler_ScheduleRespectsCapacity(t *testing.T) {
+	fifo := NewFIFOScheduler()
+	fifo.SetConfig(&Config{Capacity: models.Resources{"cpu": 2}})
+
+	now := time.Now()
+	fifo.AddTasks(models.Task{Index: 0, RemainingTime: 1, CreatedTime: now, Resources: models.Resources{"cpu": 2}})
+	fifo.AddTasks(models.Task{Index: 1, RemainingTime: 1, CreatedTime: now.Add(time.Millisecond), Resources: models.Resources{"cpu": 1}})
+	fifo.AddTasks(models.Task{Index: 2, RemainingTime: 1, CreatedTime: now.Add(2 * time.Millisecond)})
+
+	scheduledTasks := fifo.Schedule(5)
+	if len(scheduledTasks) != 2 || scheduledTasks[0].Index != 0 || scheduledTasks[1].Index != 2 {
+		t.Fatalf("Expected tasks 0 and 2 to run, got %v", scheduledTasks)
+	}
+	if fifo.GetTasksLen() != 1 {
+		t.Errorf("Expected the cpu-bound task to stay queued, got %d queued", fifo.GetTasksLen())
+	}
+}
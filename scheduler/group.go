@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"fmt"
+	"scheduler-service/models"
+	"slices"
+	"strings"
+)
+
+// group is a node of an engine's scheduling tree, such as a tenant, a team
+// or a job. It queues its own tasks under the current strategy of its
+// SchedulerManager, and its policy divides the bandwidth the group gets in
+// a cycle between that queue and its subgroups.
+type group struct {
+	manager  *SchedulerManager
+	weight   int
+	policy   string // TenantFair or TenantPriority
+	children map[string]*group
+	names    []string // 按名称排序
+	bucket   *bucket  // 租户的突发额度，其他组为nil
+}
+
+// GroupInfo describes one group of a tenant's tree.
+type GroupInfo struct {
+	Class   string // service class, "" without classes
+	Path    string // relative to the tenant, "" for the tenant itself
+	Weight  int
+	Policy  string
+	Manager *SchedulerManager // the group's own queue and strategies
+}
+
+// ParseGroupPath splits a group path like "eng/search" into its names.
+// The empty path is the tenant itself.
+func ParseGroupPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	names := strings.Split(path, "/")
+	for _, name := range names {
+		if strings.TrimSpace(name) != name || name == "" {
+			return nil, fmt.Errorf("invalid group path %q", path)
+		}
+	}
+	return names, nil
+}
+
+// queued returns the number of tasks queued in the group and its subgroups.
+func (g *group) queued() int {
+	total := g.manager.GetCurrentScheduler().GetTasksLen()
+	for _, child := range g.children {
+		total += child.queued()
+	}
+	return total
+}
+
+// reserved returns the widest task of the group and its subgroups that
+// could not run in the last cycle for lack of bandwidth, 0 without one.
+func (g *group) reserved() int {
+	width := g.manager.GetCurrentScheduler().Reserved()
+	for _, child := range g.children {
+		width = max(width, child.reserved())
+	}
+	return width
+}
+
+// walk calls fn for the group and its subgroups, depth first in name order.
+func (g *group) walk(path string, fn func(path string, g *group)) {
+	fn(path, g)
+	for _, name := range g.names {
+		childPath := name
+		if path != "" {
+			childPath = path + "/" + name
+		}
+		g.children[name].walk(childPath, fn)
+	}
+}
+
+// schedule runs cycle tick+1 for the group's own queue and its subgroups
+// with queued tasks. Its own queue takes part with weight 1 ahead of the
+// subgroups; bandwidth and capacity one of them leaves unused pass on to
+// the next. A member with burst credits may take that many units above its
+// share and pays for them. A member holding a task too wide for its share
+// gets the task's width, which the other members hold back for it.
+func (g *group) schedule(bandwidth int, capacity models.Resources, tick int) []*models.Task {
+	if len(g.children) == 0 {
+		return g.manager.schedule(bandwidth, capacity, tick+1)
+	}
+
+	var active []*group
+	weight := 0
+	if g.manager.GetCurrentScheduler().GetTasksLen() > 0 {
+		active = append(active, &group{manager: g.manager, weight: 1})
+		weight++
+	}
+	for _, name := range g.names {
+		if child := g.children[name]; child.queued() > 0 {
+			active = append(active, child)
+			weight += child.weight
+		}
+	}
+	if g.policy == TenantPriority {
+		slices.SortStableFunc(active, func(a, b *group) int { return b.weight - a.weight })
+	} else if len(active) > 0 {
+		// 轮换顺序，使取整余下的带宽不总是落到同一个成员
+		shift := tick % len(active)
+		active = append(active[shift:], active[:shift]...)
+	}
+
+	// 上个周期因带宽不足受阻的宽任务向其他成员借用带宽，否则份额小于
+	// 其宽度时永远无法运行
+	held := 0
+	for _, member := range active {
+		held += member.reserved()
+	}
+
+	var scheduledTasks []*models.Task
+	remaining := bandwidth
+	free := capacity.Clone()
+	for _, member := range active {
+		share := remaining
+		if g.policy != TenantPriority {
+			share = remaining * member.weight / weight
+			weight -= member.weight
+		}
+		if width := member.reserved(); width > 0 {
+			share = max(share, min(width, remaining))
+			held -= width
+		} else {
+			share = min(share, max(remaining-held, 0))
+		}
+		fair := share
+		if member.bucket != nil {
+			// 有额度时可以超出公平份额
+			share += min(int(member.bucket.credits), remaining-share)
+		}
+		used := 0
+		for _, task := range member.schedule(share, free.Clone(), tick) {
+			used += task.Allocated
+			free.Sub(task.Resources)
+			scheduledTasks = append(scheduledTasks, task)
+		}
+		if member.bucket != nil {
+			member.bucket.spend(used - fair)
+		}
+		remaining = max(remaining-used, 0)
+	}
+	return scheduledTasks
+}
@@ -6,6 +6,19 @@ type Scheduler interface {
 	Schedule(bandwidth int) []*models.Task
 	GetName() string
 	AddTasks(task models.Task)
+	AddBatch(tasks []models.Task)
 	GetNextTask() (models.Task, bool)
+	Peek() (models.Task, bool)
 	GetTasksLen() int
+	Tasks() []models.Task
+	Head(n int) []models.Task
+	UpdateTask(index int, update func(task *models.Task)) bool
+	RemoveTask(index int) (models.Task, bool)
+	SetConfig(config *Config)
+	SetPreemptive(preemptive bool)
+	IsPreemptive() bool
+	// Reserved returns the width of a task that could not run in the last
+	// cycle for lack of bandwidth, 0 without one. The group above lends it
+	// bandwidth from the siblings when it exceeds the queue's share.
+	Reserved() int
 }
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Partition is a share of every tenant's bandwidth served by its own
+// strategy, such as an SRTF queue for interactive tasks next to a FIFO
+// queue for batch tasks. Every tenant gets a group per partition.
+type Partition struct {
+	Name     string
+	Strategy string
+	// Bandwidth is the partition's share in units; a tenant's share is
+	// divided in proportion, and what an idle partition leaves unused is
+	// lent to the others.
+	Bandwidth int
+	// Match routes tasks carrying all these labels to the partition. The
+	// partition without labels takes the tasks no other one matches.
+	Match map[string]string
+}
+
+// ParsePartitions parses a comma separated list like
+// "interactive=SRTF:3:tier=interactive,batch=FIFO:2".
+func ParsePartitions(s string) ([]Partition, error) {
+	var partitions []Partition
+	if strings.TrimSpace(s) == "" {
+		return partitions, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		name, spec, ok := strings.Cut(strings.TrimSpace(item), "=")
+		fields := strings.SplitN(spec, ":", 3)
+		if !ok || len(fields) < 2 {
+			return nil, fmt.Errorf("invalid partition %q, expected name=STRATEGY:units[:label=value]", item)
+		}
+		bandwidth, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bandwidth for partition %s: %q", name, fields[1])
+		}
+		partition := Partition{Name: name, Strategy: fields[0], Bandwidth: bandwidth}
+		if len(fields) == 3 {
+			key, value, ok := strings.Cut(fields[2], "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid label for partition %s: %q", name, fields[2])
+			}
+			partition.Match = map[string]string{key: value}
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions, nil
+}
+
+// validatePartitions checks partitions against the strategies an engine offers.
+func validatePartitions(partitions []Partition, manager *SchedulerManager) error {
+	if len(partitions) == 0 {
+		return nil
+	}
+	names := make(map[string]bool, len(partitions))
+	catchAll := 0
+	for _, p := range partitions {
+		if p.Name == "" || strings.Contains(p.Name, "/") || names[p.Name] {
+			return fmt.Errorf("invalid or duplicate partition name %q", p.Name)
+		}
+		names[p.Name] = true
+		if _, exists := manager.schedulers[p.Strategy]; !exists {
+			return fmt.Errorf("unsupported scheduler strategy for partition %s: %s", p.Name, p.Strategy)
+		}
+		if p.Bandwidth <= 0 {
+			return fmt.Errorf("partition %s needs a positive bandwidth: %d", p.Name, p.Bandwidth)
+		}
+		if len(p.Match) == 0 {
+			catchAll++
+		}
+	}
+	if catchAll != 1 {
+		return fmt.Errorf("exactly one partition must match any task, got %d", catchAll)
+	}
+	return nil
+}
+
+// matches reports whether labels carry every label the partition matches.
+func (p Partition) matches(labels map[string]string) bool {
+	for key, value := range p.Match {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
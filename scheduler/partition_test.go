@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestParsePartitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		count   int
+		wantErr bool
+	}{
+		{"Empty", "", 0, false},
+		{"Two partitions", "interactive=SRTF:3:tier=interactive,batch=FIFO:2", 2, false},
+		{"Missing bandwidth", "batch=FIFO", 0, true},
+		{"Invalid bandwidth", "batch=FIFO:x", 0, true},
+		{"Invalid label", "interactive=SRTF:3:tier", 0, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			partitions, err := ParsePartitions(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if len(partitions) != tc.count {
+				t.Errorf("Expected %d partitions, got %+v", tc.count, partitions)
+			}
+		})
+	}
+}
+
+func TestNewEngine_Partitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"Valid", "interactive=SRTF:3:tier=interactive,batch=FIFO:2", false},
+		{"No catch-all", "interactive=SRTF:3:tier=interactive", true},
+		{"Two catch-alls", "a=FIFO:1,b=FIFO:1", true},
+		{"Duplicate name", "a=FIFO:1:tier=x,a=FIFO:1", true},
+		{"Unknown strategy", "a=LIFO:1", true},
+		{"Zero bandwidth", "a=FIFO:0", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			partitions, err := ParsePartitions(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_, err = NewEngine(EngineOptions{Bandwidth: 5, Partitions: partitions})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEngine_Partitions(t *testing.T) {
+	partitions, _ := ParsePartitions("interactive=SRTF:3:tier=interactive,batch=FIFO:2")
+
+	tests := []struct {
+		name     string
+		tasks    map[string]int // 每种标签排队的任务数
+		expected map[string]int // 每个分区本周期分到的带宽
+	}{
+		{"Both busy", map[string]int{"interactive": 5, "batch": 5}, map[string]int{"interactive": 3, "batch": 2}},
+		{"Idle partition lends its share", map[string]int{"batch": 5}, map[string]int{"batch": 5}},
+		{"Other labels go to batch", map[string]int{"interactive": 5, "nightly": 5}, map[string]int{"interactive": 3, "batch": 2}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, err := NewEngine(EngineOptions{Bandwidth: 5, Partitions: partitions})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for tier, count := range tc.tasks {
+				for i := 0; i < count; i++ {
+					task := engine.NewTask(10)
+					task.Labels = map[string]string{"tier": tier}
+					task.Group = engine.Route(task.Labels, "")
+					task.MaxParallelism = 1
+					engine.Group("a", task.Group).GetCurrentScheduler().AddTasks(*task)
+				}
+			}
+
+			allocated := make(map[string]int)
+			for _, task := range engine.Schedule() {
+				allocated[task.Group] += task.Allocated
+			}
+			for partition, units := range tc.expected {
+				if allocated[partition] != units {
+					t.Errorf("Expected partition %s to get %d units, got %v", partition, units, allocated)
+				}
+			}
+		})
+	}
+
+	engine, _ := NewEngine(EngineOptions{Bandwidth: 5, Partitions: partitions})
+	engine.Tenant("a")
+	groups := engine.Groups("a")
+	if len(groups) != 3 || groups[2].Path != "interactive" || groups[2].Manager.GetCurrentScheduler().GetName() != "SRTF" {
+		t.Errorf("Expected every tenant to get the partition groups, got %+v", groups)
+	}
+	if path := engine.Route(map[string]string{"tier": "interactive"}, "eng"); path != "interactive/eng" {
+		t.Errorf("Expected groups to sit within the partition, got %s", path)
+	}
+}
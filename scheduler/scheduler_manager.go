@@ -8,21 +8,56 @@ import (
 type SchedulerManager struct {
 	schedulers map[string]Scheduler
 	current    Scheduler
+	config     *Config
+}
+
+// strategies constructs a scheduler for every supported strategy name.
+var strategies = map[string]func() Scheduler{
+	"FIFO":       func() Scheduler { return NewFIFOScheduler() },
+	"SRTF":       func() Scheduler { return NewSRTFScheduler() },
+	"BESTFIT":    func() Scheduler { return NewBestFitScheduler() },
+	"THROUGHPUT": func() Scheduler { return NewThroughputScheduler() },
+	"EASY":       func() Scheduler { return NewEASYScheduler() },
+	"EDF":        func() Scheduler { return NewEDFScheduler() },
 }
 
 func NewSchedulerManager() *SchedulerManager {
-	fifoScheduler := NewFIFOScheduler()
-	srtfScheduler := NewSRTFScheduler()
+	sm, _ := NewSchedulerManagerFor(nil)
+	return sm
+}
+
+// NewSchedulerManagerFor offers only the named strategies, starting with
+// the first one. Without names it offers every strategy, starting with FIFO.
+func NewSchedulerManagerFor(names []string) (*SchedulerManager, error) {
+	if len(names) == 0 {
+		names = []string{"FIFO"} // 默认使用FIFO
+		for name := range strategies {
+			if name != "FIFO" {
+				names = append(names, name)
+			}
+		}
+	}
 
-	schedulers := map[string]Scheduler{
-		"FIFO": fifoScheduler,
-		"SRTF": srtfScheduler,
+	schedulers := make(map[string]Scheduler, len(names))
+	for _, name := range names {
+		newScheduler, exists := strategies[name]
+		if !exists {
+			return nil, fmt.Errorf("unsupported scheduler strategy: %s", name)
+		}
+		schedulers[name] = newScheduler()
+	}
+
+	// 所有调度器共享同一份配置
+	config := &Config{}
+	for _, s := range schedulers {
+		s.SetConfig(config)
 	}
 
 	return &SchedulerManager{
 		schedulers: schedulers,
-		current:    fifoScheduler, // 默认使用FIFO
-	}
+		current:    schedulers[names[0]],
+		config:     config,
+	}, nil
 }
 
 func (sm *SchedulerManager) SwitchScheduler(strategy string) error {
@@ -58,6 +93,48 @@ func (sm *SchedulerManager) migrateTasks(oldScheduler, newScheduler Scheduler) {
 	}
 }
 
+// SetCapacity sets the multi-dimensional resource capacity available in each cycle.
+func (sm *SchedulerManager) SetCapacity(capacity models.Resources) {
+	sm.config.Capacity = capacity.Clone()
+}
+
+func (sm *SchedulerManager) GetCapacity() models.Resources {
+	return sm.config.Capacity.Clone()
+}
+
+// SetFailureModel sets the model used to simulate task failures.
+func (sm *SchedulerManager) SetFailureModel(failure FailureModel) {
+	sm.config.Failure = failure
+}
+
+// SetEstimateModel sets the model used to revise predicted estimates while tasks run.
+func (sm *SchedulerManager) SetEstimateModel(estimator EstimateModel) {
+	sm.config.Estimator = estimator
+}
+
+// SetContextSwitch sets the bandwidth a task loses when it joins the running set.
+func (sm *SchedulerManager) SetContextSwitch(cost int, mode string) error {
+	return sm.config.setContextSwitch(cost, mode)
+}
+
+// SetPreemptive turns preemption on or off for one strategy.
+func (sm *SchedulerManager) SetPreemptive(strategy string, preemptive bool) error {
+	s, exists := sm.schedulers[strategy]
+	if !exists {
+		return fmt.Errorf("unsupported scheduler strategy: %s", strategy)
+	}
+	s.SetPreemptive(preemptive)
+	return nil
+}
+
+// schedule runs the current strategy for one engine cycle with the bandwidth
+// and resource capacity the engine left to this manager.
+func (sm *SchedulerManager) schedule(bandwidth int, capacity models.Resources, cycle int) []*models.Task {
+	sm.config.Capacity = capacity
+	sm.config.cycle = cycle - 1 // beginCycle moves it to cycle
+	return sm.current.Schedule(bandwidth)
+}
+
 func (sm *SchedulerManager) GetCurrentScheduler() Scheduler {
 	return sm.current
 }
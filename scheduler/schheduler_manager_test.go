@@ -15,7 +15,7 @@ func TestNewSchedulerManager(t *testing.T) {
 
 	// 验证可用的调度策略
 	strategies := manager.GetAvailableStrategies()
-	expectedStrategies := map[string]bool{"FIFO": true, "SRTF": true}
+	expectedStrategies := map[string]bool{"FIFO": true, "SRTF": true, "BESTFIT": true, "THROUGHPUT": true, "EASY": true, "EDF": true}
 
 	if len(strategies) != len(expectedStrategies) {
 		t.Errorf("Expected %d strategies, got %d",
@@ -55,3 +55,36 @@ func TestSchedulerManager_SwitchScheduler(t *testing.T) {
 			manager.GetCurrentScheduler().GetName())
 	}
 }
+
+func TestSchedulerManager_ExecutionOptions(t *testing.T) {
+	manager := NewSchedulerManager()
+
+	if err := manager.SetContextSwitch(-1, SwitchOnResume); err == nil {
+		t.Error("Expected error for negative switch cost, got nil")
+	}
+	if err := manager.SetContextSwitch(1, "sometimes"); err == nil {
+		t.Error("Expected error for unknown switch mode, got nil")
+	}
+	if err := manager.SetContextSwitch(1, ""); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := manager.SetPreemptive("INVALID", false); err == nil {
+		t.Error("Expected error for invalid scheduler, got nil")
+	}
+	if err := manager.SetPreemptive("SRTF", false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := manager.SwitchScheduler("SRTF"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if manager.GetCurrentScheduler().IsPreemptive() {
+		t.Error("Expected SRTF to be non-preemptive")
+	}
+
+	// EASY始终是非抢占的
+	manager.SetPreemptive("EASY", true)
+	if manager.schedulers["EASY"].IsPreemptive() {
+		t.Error("Expected EASY to stay non-preemptive")
+	}
+}
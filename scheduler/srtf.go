@@ -9,37 +9,21 @@ type SRTFScheduler struct {
 }
 
 func NewSRTFScheduler() *SRTFScheduler {
-	heap := make(SRTFTaskHeap, 0)
-	baseScheduler := NewBaseScheduler(&heap, "SRTF")
+	baseScheduler := NewBaseScheduler(srtfLess, "SRTF")
 	return &SRTFScheduler{
 		BaseScheduler: baseScheduler,
 	}
 }
 
-type SRTFTaskHeap []models.Task
-
-func (h SRTFTaskHeap) Len() int {
-	return len(h)
-}
-
-func (h SRTFTaskHeap) Less(i, j int) bool {
-	if h[i].RemainingTime == h[j].RemainingTime {
-		return h[i].Index < h[j].Index
+// srtfLess orders by estimated remaining time, since the actual runtime is
+// unknown until the task completes.
+func srtfLess(a, b *models.Task) bool {
+	if less, ok := byPriority(a, b); ok {
+		return less
 	}
-	return h[i].RemainingTime < h[j].RemainingTime
-}
-
-func (h SRTFTaskHeap) Swap(i, j int) {
-	h[i], h[j] = h[j], h[i]
-}
-func (h *SRTFTaskHeap) Push(x interface{}) {
-	*h = append(*h, x.(models.Task))
-}
-
-func (h *SRTFTaskHeap) Pop() interface{} {
-	old := *h
-	n := len(old)
-	task := old[n-1]
-	*h = old[0 : n-1]
-	return task
+	ra, rb := a.EstimatedRemaining(), b.EstimatedRemaining()
+	if ra == rb {
+		return a.Index < b.Index
+	}
+	return ra < rb
 }
@@ -131,6 +131,16 @@ func TestSRTFScheduler_Schedule(t *testing.T) {
 			expectedIndexes: []int{1},
 			expectedRemains: []int{0},
 		},
+		{
+			name: "Sort by estimate, not actual remaining time",
+			tasks: []*models.Task{
+				{Index: 0, Duration: 2, RemainingTime: 2, Estimate: 8},
+				{Index: 1, Duration: 6, RemainingTime: 6, Estimate: 3},
+			},
+			bandwidth:       5,
+			expectedIndexes: []int{1},
+			expectedRemains: []int{1},
+		},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"container/heap"
+	"scheduler-service/models"
+	"slices"
+)
+
+// Less reports whether task a runs before task b.
+type Less func(a, b *models.Task) bool
+
+// byPriority orders a before b by priority and then admin rank; ok is false
+// when both are equal and the strategy decides.
+func byPriority(a, b *models.Task) (less bool, ok bool) {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority, true
+	}
+	if a.Rank != b.Rank {
+		return a.Rank < b.Rank, true
+	}
+	return false, false
+}
+
+// TaskQueue is a binary heap of tasks indexed by task index. Push, Pop,
+// Remove and Update take O(log n); Peek and Get take O(1).
+type TaskQueue struct {
+	h indexedHeap
+}
+
+func NewTaskQueue(less Less) *TaskQueue {
+	return &TaskQueue{h: indexedHeap{pos: make(map[int]int), less: less}}
+}
+
+func (q *TaskQueue) Len() int {
+	return len(q.h.tasks)
+}
+
+// Push adds task, replacing a queued task with the same index.
+func (q *TaskQueue) Push(task models.Task) {
+	if i, exists := q.h.pos[task.Index]; exists {
+		q.h.tasks[i] = task
+		heap.Fix(&q.h, i)
+		return
+	}
+	heap.Push(&q.h, task)
+}
+
+// PushAll adds tasks in bulk. A batch at least as large as the queue is
+// appended and heapified in O(n) instead of pushed one by one.
+func (q *TaskQueue) PushAll(tasks []models.Task) {
+	if len(tasks) < q.Len() {
+		for _, task := range tasks {
+			q.Push(task)
+		}
+		return
+	}
+	if q.Len() == 0 {
+		q.h.pos = make(map[int]int, len(tasks))
+	}
+	q.h.tasks = slices.Grow(q.h.tasks, len(tasks))
+	for _, task := range tasks {
+		if i, exists := q.h.pos[task.Index]; exists {
+			q.h.tasks[i] = task
+			continue
+		}
+		q.h.pos[task.Index] = len(q.h.tasks)
+		q.h.tasks = append(q.h.tasks, task)
+	}
+	heap.Init(&q.h)
+}
+
+// Pop removes and returns the first task.
+func (q *TaskQueue) Pop() (models.Task, bool) {
+	if q.Len() == 0 {
+		return models.Task{}, false
+	}
+	return heap.Pop(&q.h).(models.Task), true
+}
+
+// Peek returns the first task without removing it.
+func (q *TaskQueue) Peek() (models.Task, bool) {
+	if q.Len() == 0 {
+		return models.Task{}, false
+	}
+	return q.h.tasks[0], true
+}
+
+// Get returns the queued task with the given index.
+func (q *TaskQueue) Get(index int) (models.Task, bool) {
+	i, exists := q.h.pos[index]
+	if !exists {
+		return models.Task{}, false
+	}
+	return q.h.tasks[i], true
+}
+
+// Remove removes and returns the queued task with the given index.
+func (q *TaskQueue) Remove(index int) (models.Task, bool) {
+	i, exists := q.h.pos[index]
+	if !exists {
+		return models.Task{}, false
+	}
+	return heap.Remove(&q.h, i).(models.Task), true
+}
+
+// Update applies update to the queued task with the given index and
+// restores the order. The update must not change the task's index.
+func (q *TaskQueue) Update(index int, update func(task *models.Task)) bool {
+	i, exists := q.h.pos[index]
+	if !exists {
+		return false
+	}
+	update(&q.h.tasks[i])
+	heap.Fix(&q.h, i)
+	return true
+}
+
+// Tasks returns a copy of the queued tasks in the order they would be popped.
+func (q *TaskQueue) Tasks() []models.Task {
+	return q.Head(q.Len())
+}
+
+// Head returns copies of the first n tasks in the order they would be
+// popped, without changing the queue. It walks the heap best-first, so the
+// cost depends on n rather than on the length of the queue.
+func (q *TaskQueue) Head(n int) []models.Task {
+	n = min(n, q.Len())
+	tasks := make([]models.Task, 0, n)
+	if n == 0 {
+		return tasks
+	}
+
+	frontier := &positionHeap{h: &q.h, positions: []int{0}}
+	for len(tasks) < n {
+		i := heap.Pop(frontier).(int)
+		tasks = append(tasks, q.h.tasks[i])
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(q.h.tasks) {
+				heap.Push(frontier, child)
+			}
+		}
+	}
+	return tasks
+}
+
+// Clone returns an independent copy of the queue.
+func (q *TaskQueue) Clone() *TaskQueue {
+	pos := make(map[int]int, len(q.h.pos))
+	for index, i := range q.h.pos {
+		pos[index] = i
+	}
+	return &TaskQueue{h: indexedHeap{tasks: slices.Clone(q.h.tasks), pos: pos, less: q.h.less}}
+}
+
+// indexedHeap implements heap.Interface and tracks the position of every task.
+type indexedHeap struct {
+	tasks []models.Task
+	pos   map[int]int
+	less  Less
+}
+
+func (h *indexedHeap) Len() int {
+	return len(h.tasks)
+}
+
+func (h *indexedHeap) Less(i, j int) bool {
+	return h.less(&h.tasks[i], &h.tasks[j])
+}
+
+func (h *indexedHeap) Swap(i, j int) {
+	h.tasks[i], h.tasks[j] = h.tasks[j], h.tasks[i]
+	h.pos[h.tasks[i].Index] = i
+	h.pos[h.tasks[j].Index] = j
+}
+
+func (h *indexedHeap) Push(x interface{}) {
+	task := x.(models.Task)
+	h.pos[task.Index] = len(h.tasks)
+	h.tasks = append(h.tasks, task)
+}
+
+func (h *indexedHeap) Pop() interface{} {
+	n := len(h.tasks)
+	task := h.tasks[n-1]
+	h.tasks = h.tasks[:n-1]
+	delete(h.pos, task.Index)
+	return task
+}
+
+// positionHeap orders positions in an indexedHeap by the tasks at them.
+type positionHeap struct {
+	h         *indexedHeap
+	positions []int
+}
+
+func (p *positionHeap) Len() int {
+	return len(p.positions)
+}
+
+func (p *positionHeap) Less(i, j int) bool {
+	return p.h.Less(p.positions[i], p.positions[j])
+}
+
+func (p *positionHeap) Swap(i, j int) {
+	p.positions[i], p.positions[j] = p.positions[j], p.positions[i]
+}
+
+func (p *positionHeap) Push(x interface{}) {
+	p.positions = append(p.positions, x.(int))
+}
+
+func (p *positionHeap) Pop() interface{} {
+	n := len(p.positions)
+	i := p.positions[n-1]
+	p.positions = p.positions[:n-1]
+	return i
+}
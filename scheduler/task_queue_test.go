@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+)
+
+func newTestQueue(remaining ...int) *TaskQueue {
+	queue := NewTaskQueue(srtfLess)
+	for i, r := range remaining {
+		queue.Push(models.Task{Index: i, RemainingTime: r})
+	}
+	return queue
+}
+
+func indexes(tasks []models.Task) []int {
+	result := make([]int, len(tasks))
+	for i, task := range tasks {
+		result[i] = task.Index
+	}
+	return result
+}
+
+func TestTaskQueue_Order(t *testing.T) {
+	queue := newTestQueue(5, 1, 4, 2, 3)
+
+	if task, ok := queue.Peek(); !ok || task.Index != 1 {
+		t.Errorf("Expected to peek task 1, got %d", task.Index)
+	}
+
+	// 有序遍历不改变队列
+	ordered := indexes(queue.Tasks())
+	expected := []int{1, 3, 4, 2, 0}
+	for i := range expected {
+		if ordered[i] != expected[i] {
+			t.Fatalf("Expected order %v, got %v", expected, ordered)
+		}
+	}
+	if queue.Len() != 5 {
+		t.Fatalf("Expected Tasks to leave 5 queued tasks, got %d", queue.Len())
+	}
+
+	for _, index := range expected {
+		task, ok := queue.Pop()
+		if !ok || task.Index != index {
+			t.Errorf("Expected to pop task %d, got %d", index, task.Index)
+		}
+	}
+	if _, ok := queue.Pop(); ok {
+		t.Error("Expected an empty queue")
+	}
+}
+
+func TestTaskQueue_RemoveAndUpdate(t *testing.T) {
+	queue := newTestQueue(5, 1, 4, 2, 3)
+
+	if task, ok := queue.Remove(3); !ok || task.RemainingTime != 2 {
+		t.Errorf("Expected to remove task 3, got %+v", task)
+	}
+	if _, ok := queue.Remove(3); ok {
+		t.Error("Expected task 3 to be gone")
+	}
+	if _, ok := queue.Get(3); ok {
+		t.Error("Expected Get to miss a removed task")
+	}
+
+	if !queue.Update(0, func(task *models.Task) { task.RemainingTime = 0 }) {
+		t.Fatal("Expected task 0 to be updated")
+	}
+	if queue.Update(7, func(task *models.Task) {}) {
+		t.Error("Expected unknown task not to be updated")
+	}
+
+	// 重复的索引替换原任务
+	queue.Push(models.Task{Index: 2, RemainingTime: 9})
+	if queue.Len() != 4 {
+		t.Fatalf("Expected 4 queued tasks, got %d", queue.Len())
+	}
+
+	ordered := indexes(queue.Tasks())
+	expected := []int{0, 1, 4, 2}
+	for i := range expected {
+		if ordered[i] != expected[i] {
+			t.Fatalf("Expected order %v, got %v", expected, ordered)
+		}
+	}
+}
+
+func TestTaskQueue_Clone(t *testing.T) {
+	queue := newTestQueue(3, 1, 2)
+	clone := queue.Clone()
+
+	clone.Pop()
+	clone.Update(0, func(task *models.Task) { task.RemainingTime = 0 })
+
+	if queue.Len() != 3 {
+		t.Errorf("Expected the original queue to keep 3 tasks, got %d", queue.Len())
+	}
+	if task, _ := queue.Get(0); task.RemainingTime != 3 {
+		t.Errorf("Expected the original task to be unchanged, got %d", task.RemainingTime)
+	}
+	if task, _ := clone.Peek(); task.Index != 0 {
+		t.Errorf("Expected the clone to reorder independently, got %d", task.Index)
+	}
+}
+
+func TestTaskQueue_PushAllAndHead(t *testing.T) {
+	queue := newTestQueue(5, 1)
+	batch := []models.Task{
+		{Index: 2, RemainingTime: 4},
+		{Index: 3, RemainingTime: 2},
+		{Index: 1, RemainingTime: 6}, // 替换已排队的任务
+	}
+	queue.PushAll(batch)
+
+	if queue.Len() != 4 {
+		t.Fatalf("Expected 4 queued tasks, got %d", queue.Len())
+	}
+	head := indexes(queue.Head(3))
+	expected := []int{3, 2, 0}
+	for i := range expected {
+		if head[i] != expected[i] {
+			t.Fatalf("Expected head %v, got %v", expected, head)
+		}
+	}
+	if len(queue.Head(10)) != 4 {
+		t.Error("Expected Head to stop at the queue length")
+	}
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+)
+
+// ThroughputScheduler hands out bandwidth one step at a time to the queued
+// task that gains the most work per unit, following each task's speedup
+// curve. Ties go to the oldest task, so linear tasks are served like FIFO.
+type ThroughputScheduler struct {
+	*BaseScheduler
+}
+
+func NewThroughputScheduler() *ThroughputScheduler {
+	baseScheduler := NewBaseScheduler(fifoLess, "THROUGHPUT")
+	return &ThroughputScheduler{
+		BaseScheduler: baseScheduler,
+	}
+}
+
+func (s *ThroughputScheduler) Schedule(bandwidth int) []*models.Task {
+	s.beginCycle()
+	s.reservation = nil
+	candidates := s.takeRunning()
+	started := len(candidates)
+	for s.queue.Len() > 0 && len(candidates) < started+bestFitWindow {
+		task, _ := s.queue.Pop()
+		candidates = append(candidates, task)
+	}
+
+	free := s.config.Capacity.Clone()
+	allocations := make([]int, len(candidates))
+	left := bandwidth
+
+	// 非抢占模式下先只在已开始的任务之间分配，再放开给所有任务
+	for _, limit := range []int{started, len(candidates)} {
+		for left > 0 {
+			best, bestStep := -1, 0
+			bestGain := 0.0
+			for i := range candidates[:limit] {
+				step, gain, ok := nextStep(&candidates[i], allocations[i], left, free)
+				if ok && (best == -1 || gain > bestGain) {
+					best, bestStep, bestGain = i, step, gain
+				}
+			}
+			if best == -1 {
+				break
+			}
+			if allocations[best] == 0 {
+				free.Sub(candidates[best].Resources)
+			}
+			allocations[best] += bestStep
+			left -= bestStep
+		}
+	}
+
+	var scheduledTasks []*models.Task
+	for i := range candidates {
+		task := &candidates[i]
+		if allocations[i] > 0 {
+			s.run(task, allocations[i])
+			scheduled := *task
+			scheduledTasks = append(scheduledTasks, &scheduled)
+		} else {
+			s.reserveWide(*task, bandwidth)
+		}
+		s.requeue(*task)
+	}
+
+	return scheduledTasks
+}
+
+// nextStep returns how many more units task can take on top of allocated
+// and the work gained per unit by taking them.
+func nextStep(task *models.Task, allocated, left int, free models.Resources) (int, float64, bool) {
+	if allocated == 0 && !task.Resources.Fits(free) {
+		return 0, 0, false
+	}
+
+	var step int
+	switch {
+	case task.Width > 0:
+		if allocated > 0 {
+			return 0, 0, false
+		}
+		step = task.Width
+	case allocated == 0:
+		step = max(task.MinParallelism, 1)
+	default:
+		step = 1
+	}
+
+	if step > left || (task.MaxParallelism > 0 && allocated+step > task.MaxParallelism) {
+		return 0, 0, false
+	}
+	gain := (task.Progress(allocated+step) - task.Progress(allocated)) / float64(step)
+	if gain <= 0 && allocated > 0 {
+		return 0, 0, false
+	}
+	return step, gain, true
+}
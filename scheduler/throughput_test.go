@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"scheduler-service/models"
+	"testing"
+	"time"
+)
+
+func TestThroughputScheduler_GetName(t *testing.T) {
+	throughput := NewThroughputScheduler()
+	if throughput.GetName() != "THROUGHPUT" {
+		t.Errorf("Expected scheduler name to be THROUGHPUT, got %s", throughput.GetName())
+	}
+}
+
+func TestThroughputScheduler_Schedule(t *testing.T) {
+	now := time.Now()
+	amdahl := &models.SpeedupModel{Model: models.SpeedupAmdahl, SerialFraction: 0.5}
+
+	tests := []struct {
+		name                string
+		tasks               []models.Task
+		bandwidth           int
+		expectedIndexes     []int
+		expectedAllocations []int
+	}{
+		{
+			name: "Linear tasks are served in FIFO order",
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 3, CreatedTime: now},
+				{Index: 1, RemainingTime: 4, CreatedTime: now.Add(time.Millisecond)},
+			},
+			bandwidth:           5,
+			expectedIndexes:     []int{0, 1},
+			expectedAllocations: []int{3, 2},
+		},
+		{
+			name: "Extra units go where they speed up most",
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 100, CreatedTime: now, Speedup: amdahl},
+				{Index: 1, RemainingTime: 100, CreatedTime: now.Add(time.Millisecond)},
+			},
+			bandwidth:           4,
+			expectedIndexes:     []int{0, 1},
+			expectedAllocations: []int{1, 3},
+		},
+		{
+			name: "Min and max parallelism",
+			tasks: []models.Task{
+				{Index: 0, RemainingTime: 100, CreatedTime: now, MaxParallelism: 1},
+				{Index: 1, RemainingTime: 100, CreatedTime: now.Add(time.Millisecond), MinParallelism: 5},
+				{Index: 2, RemainingTime: 100, CreatedTime: now.Add(2 * time.Millisecond), MinParallelism: 2},
+			},
+			bandwidth:           4,
+			expectedIndexes:     []int{0, 2},
+			expectedAllocations: []int{1, 3},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			throughput := NewThroughputScheduler()
+			for _, task := range tc.tasks {
+				throughput.AddTasks(task)
+			}
+
+			scheduledTasks := throughput.Schedule(tc.bandwidth)
+
+			if len(scheduledTasks) != len(tc.expectedIndexes) {
+				t.Fatalf("Expected %d scheduled tasks, got %d",
+					len(tc.expectedIndexes), len(scheduledTasks))
+			}
+			for i, task := range scheduledTasks {
+				if task.Index != tc.expectedIndexes[i] || task.Allocated != tc.expectedAllocations[i] {
+					t.Errorf("Expected task %d with %d units, got task %d with %d units",
+						tc.expectedIndexes[i], tc.expectedAllocations[i], task.Index, task.Allocated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"scheduler-service/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Quota reasons reported by QuotaError.
+const (
+	ReasonQueuedTasks     = "max_queued_tasks"
+	ReasonOutstandingWork = "max_outstanding_work"
+	ReasonTaskDuration    = "max_task_duration"
+	ReasonParkedTasks     = "max_parked_tasks"
+)
+
+// QuotaError reports a submission over a tenant quota.
+type QuotaError struct {
+	Tenant    string
+	Reason    string // the exceeded limit
+	Limit     int
+	Requested int // what the tenant would reach with the submission
+	// RetryAfter estimates when the submission could fit; 0 when waiting cannot help.
+	RetryAfter time.Duration
+	// work is what must complete before the submission fits, 0 when waiting
+	// cannot help; the service turns it into RetryAfter.
+	work int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %s exceeds %s: %d > %d", e.Tenant, e.Reason, e.Requested, e.Limit)
+}
+
+// tenantUsage is what counts against a tenant's quota: its queued, delayed
+// and not yet merged tasks.
+type tenantUsage struct {
+	queued int
+	work   int // 剩余工作量之和
+}
+
+// admission enforces tenant quotas and keeps the tasks parked until they
+// fit. It has its own lock so that submissions never wait for a cycle;
+// callers holding the service lock may take it, never the other way round.
+type admission struct {
+	mu     sync.Mutex
+	config models.QuotaConfig
+	usage  map[string]*tenantUsage
+	parked map[string][]models.Task // 每个租户按提交顺序等待准入的任务
+}
+
+func newAdmission() *admission {
+	return &admission{
+		usage:  make(map[string]*tenantUsage),
+		parked: make(map[string][]models.Task),
+	}
+}
+
+// LoadQuotaConfig reads tenant quotas from a JSON file.
+func LoadQuotaConfig(path string) (models.QuotaConfig, error) {
+	var config models.QuotaConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid quota file %s: %w", path, err)
+	}
+	return config, config.Validate()
+}
+
+func (a *admission) setConfig(config models.QuotaConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.config = config
+}
+
+// setQuota replaces the quota of tenant and returns the previous one.
+func (a *admission) setQuota(tenant string, quota models.Quota) models.Quota {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	previous := a.config.For(tenant)
+	// 配置可能与调用方共享，复制后再修改
+	tenants := make(map[string]models.Quota, len(a.config.Tenants)+1)
+	maps.Copy(tenants, a.config.Tenants)
+	tenants[tenant] = quota
+	a.config.Tenants = tenants
+	return previous
+}
+
+func (a *admission) tenantUsage(tenant string) *tenantUsage {
+	usage, exists := a.usage[tenant]
+	if !exists {
+		usage = &tenantUsage{}
+		a.usage[tenant] = usage
+	}
+	return usage
+}
+
+// reserve counts tasks of one submission against the tenant's quota and
+// returns the tasks admitted right away. Over the quota, the rest is parked
+// or, in reject mode or past the parked task limit, the whole submission
+// fails with a QuotaError.
+func (a *admission) reserve(tenant string, tasks []models.Task) ([]models.Task, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	quota := a.config.For(tenant)
+	// 单个任务永远无法满足的限制，等待也没有用
+	for _, task := range tasks {
+		if quota.MaxTaskDuration > 0 && task.Duration > quota.MaxTaskDuration {
+			return nil, &QuotaError{Tenant: tenant, Reason: ReasonTaskDuration, Limit: quota.MaxTaskDuration, Requested: task.Duration}
+		}
+		if quota.MaxOutstandingWork > 0 && task.Duration > quota.MaxOutstandingWork {
+			return nil, &QuotaError{Tenant: tenant, Reason: ReasonOutstandingWork, Limit: quota.MaxOutstandingWork, Requested: task.Duration}
+		}
+	}
+
+	usage := a.tenantUsage(tenant)
+	if quota.OnExceed == models.QuotaPark && len(a.parked[tenant]) > 0 {
+		// 保持提交顺序，排在已停放的任务之后
+		if err := a.overParked(tenant, quota, tasks); err != nil {
+			return nil, err
+		}
+		a.parked[tenant] = append(a.parked[tenant], tasks...)
+		return nil, nil
+	}
+
+	fitting := 0
+	for _, task := range tasks {
+		if !fits(quota, usage, task) {
+			break
+		}
+		usage.queued++
+		usage.work += task.RemainingTime
+		fitting++
+	}
+	if fitting == len(tasks) {
+		return tasks, nil
+	}
+	// 拒绝时撤销已计入的部分
+	release := func() {
+		for _, task := range tasks[:fitting] {
+			usage.queued--
+			usage.work -= task.RemainingTime
+		}
+	}
+	if quota.OnExceed == models.QuotaPark {
+		if err := a.overParked(tenant, quota, tasks[fitting:]); err != nil {
+			release()
+			return nil, err
+		}
+		a.parked[tenant] = append(a.parked[tenant], tasks[fitting:]...)
+		return tasks[:fitting], nil
+	}
+
+	release()
+	return nil, exceeded(tenant, quota, usage, tasks)
+}
+
+// overParked returns the QuotaError for parking tasks of tenant beyond its
+// parked task limit, or nil if they fit. Waiting helps unless tasks alone
+// are over the limit: the parked tasks ahead of them have to be admitted.
+func (a *admission) overParked(tenant string, quota models.Quota, tasks []models.Task) *QuotaError {
+	parked := a.parked[tenant]
+	limit := quota.ParkedLimit()
+	requested := len(parked) + len(tasks)
+	if requested <= limit {
+		return nil
+	}
+	err := &QuotaError{Tenant: tenant, Reason: ReasonParkedTasks, Limit: limit, Requested: requested}
+	if len(tasks) <= limit {
+		for _, task := range parked[:requested-limit] {
+			err.work += task.RemainingTime
+		}
+		err.work = max(err.work, 1)
+	}
+	return err
+}
+
+func fits(quota models.Quota, usage *tenantUsage, task models.Task) bool {
+	return (quota.MaxQueuedTasks == 0 || usage.queued+1 <= quota.MaxQueuedTasks) &&
+		(quota.MaxOutstandingWork == 0 || usage.work+task.RemainingTime <= quota.MaxOutstandingWork)
+}
+
+// exceeded describes the limit tasks exceed, with the work the tenant's
+// queue has to get done before they fit, or none if the batch would not
+// fit even an empty queue.
+func exceeded(tenant string, quota models.Quota, usage *tenantUsage, tasks []models.Task) *QuotaError {
+	work := 0
+	for _, task := range tasks {
+		work += task.RemainingTime
+	}
+
+	// 整批在空队列中也放不下时，等待没有用
+	if quota.MaxQueuedTasks > 0 && len(tasks) > quota.MaxQueuedTasks {
+		return &QuotaError{Tenant: tenant, Reason: ReasonQueuedTasks, Limit: quota.MaxQueuedTasks, Requested: len(tasks)}
+	}
+	if quota.MaxOutstandingWork > 0 && work > quota.MaxOutstandingWork {
+		return &QuotaError{Tenant: tenant, Reason: ReasonOutstandingWork, Limit: quota.MaxOutstandingWork, Requested: work}
+	}
+
+	err := &QuotaError{Tenant: tenant}
+	if quota.MaxQueuedTasks > 0 && usage.queued+len(tasks) > quota.MaxQueuedTasks {
+		err.Reason, err.Limit, err.Requested = ReasonQueuedTasks, quota.MaxQueuedTasks, usage.queued+len(tasks)
+		// 需要先完成的任务按平均剩余工作量估计
+		average := 1
+		if usage.queued > 0 {
+			average = max(usage.work/usage.queued, 1)
+		}
+		err.work = (err.Requested - err.Limit) * average
+	} else {
+		err.Reason, err.Limit, err.Requested = ReasonOutstandingWork, quota.MaxOutstandingWork, usage.work+work
+		err.work = err.Requested - err.Limit
+	}
+	return err
+}
+
+// unpark admits parked tasks, in submission order, as far as the quotas
+// of their tenants now allow.
+func (a *admission) unpark() []models.Task {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var admitted []models.Task
+	for tenant, parked := range a.parked {
+		quota := a.config.For(tenant)
+		usage := a.tenantUsage(tenant)
+		fitting := 0
+		for _, task := range parked {
+			if !fits(quota, usage, task) {
+				break
+			}
+			usage.queued++
+			usage.work += task.RemainingTime
+			fitting++
+		}
+		admitted = append(admitted, parked[:fitting]...)
+		if fitting == len(parked) {
+			delete(a.parked, tenant)
+		} else {
+			a.parked[tenant] = parked[fitting:]
+		}
+	}
+	return admitted
+}
+
+// update changes the usage of tenant by the given number of queued tasks and units of work.
+func (a *admission) update(tenant string, queued, work int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	usage := a.tenantUsage(tenant)
+	usage.queued += queued
+	usage.work += work
+}
+
+// cancel removes a parked task.
+func (a *admission) cancel(tenant string, index int) (models.Task, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	parked := a.parked[tenant]
+	i := slices.IndexFunc(parked, func(task models.Task) bool { return task.Index == index })
+	if i < 0 {
+		return models.Task{}, false
+	}
+	task := parked[i]
+	a.parked[tenant] = slices.Delete(parked, i, i+1)
+	return task, true
+}
+
+// parkedLen returns the number of parked tasks of tenant, or of all tenants when tenant is empty.
+func (a *admission) parkedLen(tenant string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if tenant != "" {
+		return len(a.parked[tenant])
+	}
+	total := 0
+	for _, parked := range a.parked {
+		total += len(parked)
+	}
+	return total
+}
+
+// parkedTasks returns the parked tasks of all tenants.
+func (a *admission) parkedTasks() []models.Task {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var tasks []models.Task
+	for _, parked := range a.parked {
+		tasks = append(tasks, parked...)
+	}
+	return tasks
+}
+
+// status returns the quota and usage of tenant.
+func (a *admission) status(tenant string) (models.Quota, tenantUsage, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.config.For(tenant), *a.tenantUsage(tenant), len(a.parked[tenant])
+}
+
+// retryAfter fills in the RetryAfter of a QuotaError from the work that has
+// to complete first and the tenant's share of the bandwidth. It takes the
+// service lock, which reserve may not, and only rejections pay for it.
+func (ts *TaskService) retryAfter(err error) error {
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || quotaErr.work <= 0 {
+		return err
+	}
+	ts.mu.RLock()
+	share := ts.engine.Share(quotaErr.Tenant)
+	ts.mu.RUnlock()
+	cycles := max((quotaErr.work+share-1)/share, 1)
+	quotaErr.RetryAfter = time.Duration(cycles) * cycleInterval
+	return err
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"testing"
+	"time"
+)
+
+func TestTaskService_QuotaReject(t *testing.T) {
+	service := NewTaskService(2)
+	err := service.SetQuotas(models.QuotaConfig{
+		Default: models.Quota{MaxQueuedTasks: 2, MaxTaskDuration: 10},
+		Tenants: map[string]models.Quota{"team-b": {}, "team-w": {MaxOutstandingWork: 10}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := service.SubmitTasks("team-a", []int{4, 4}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		tenant     string
+		timeSlices []int
+		reason     string
+		retryAfter time.Duration
+	}{
+		{"Over queued tasks", "team-a", []int{1}, ReasonQueuedTasks, 2 * cycleInterval},
+		{"Task too long", "team-a", []int{11}, ReasonTaskDuration, 0},
+		{"Batch over queued tasks", "team-a", []int{1, 1, 1}, ReasonQueuedTasks, 0},
+		{"Batch over outstanding work", "team-w", []int{6, 6}, ReasonOutstandingWork, 0},
+		{"Other tenant unlimited", "team-b", []int{11, 11, 11}, "", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.SubmitTasks(tc.tenant, tc.timeSlices)
+			if tc.reason == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) {
+				t.Fatalf("Expected a quota error, got %v", err)
+			}
+			if quotaErr.Reason != tc.reason || quotaErr.RetryAfter != tc.retryAfter {
+				t.Errorf("Expected %s with retry after %v, got %s with %v",
+					tc.reason, tc.retryAfter, quotaErr.Reason, quotaErr.RetryAfter)
+			}
+		})
+	}
+
+	// 被拒绝的提交不计入用量
+	if quota := service.GetQuota("team-a"); quota.QueuedTasks != 2 || quota.OutstandingWork != 8 {
+		t.Errorf("Expected 2 queued tasks with 8 units of work, got %+v", quota)
+	}
+}
+
+func TestTaskService_QuotaPark(t *testing.T) {
+	service := NewTaskService(2)
+	err := service.SetQuotas(models.QuotaConfig{
+		Default: models.Quota{MaxOutstandingWork: 4, OnExceed: models.QuotaPark},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	response, err := service.SubmitTasks("team-a", []int{2, 2, 2, 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.ParkedTasks != 2 {
+		t.Fatalf("Expected 2 parked tasks, got %d", response.ParkedTasks)
+	}
+	if status := service.GetStatus("team-a"); status.ActiveCount != 2 || status.ParkedTasks != 2 {
+		t.Fatalf("Expected 2 active and 2 parked tasks, got %d and %d", status.ActiveCount, status.ParkedTasks)
+	}
+
+	// 已停放的任务之后的提交也要排队，即使它能放下
+	if response, _ := service.SubmitTasks("team-a", []int{1}); response.ParkedTasks != 1 {
+		t.Errorf("Expected the later submission to be parked, got %d parked tasks", response.ParkedTasks)
+	}
+	parked := service.GetStatus("team-a")
+	if _, err := service.CancelTask("team-a", parked.ActiveTasks[0].Index+4); err != nil {
+		t.Errorf("Expected to cancel a parked task: %v", err)
+	}
+
+	for i := 0; i < 10 && service.HasActiveTasks(); i++ {
+		service.ExecuteSchedulingCycle()
+	}
+	status := service.GetStatus("team-a")
+	if status.ParkedTasks != 0 || len(status.CompletedTasks) != 4 {
+		t.Errorf("Expected parked tasks to be admitted and completed, got %d parked and %d completed",
+			status.ParkedTasks, len(status.CompletedTasks))
+	}
+	if quota := service.GetQuota("team-a"); quota.QueuedTasks != 0 || quota.OutstandingWork != 0 {
+		t.Errorf("Expected no usage left, got %+v", quota)
+	}
+}
+
+func TestTaskService_QuotaRetryAfterShare(t *testing.T) {
+	service := NewTaskService(4)
+	if _, err := service.SetQuota("team-a", models.Quota{MaxQueuedTasks: 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.SetTenantWeight("team-b", 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for tenant, durations := range map[string][]int{"team-a": {4}, "team-b": {100}} {
+		if _, err := service.SubmitTasks(tenant, durations); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// team-a只分到4个单位中的1个，排在前面的4个单位的工作需要4个周期
+	_, err := service.SubmitTasks("team-a", []int{1})
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || quotaErr.RetryAfter != 4*cycleInterval {
+		t.Errorf("Expected to retry after 4 cycles, got %v", err)
+	}
+}
+
+func TestTaskService_QuotaParkedLimit(t *testing.T) {
+	service := NewTaskService(2)
+	err := service.SetQuotas(models.QuotaConfig{
+		Default: models.Quota{MaxOutstandingWork: 2, OnExceed: models.QuotaPark, MaxParkedTasks: 2},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response, err := service.SubmitTasks("team-a", []int{2, 2, 2}); err != nil || response.ParkedTasks != 2 {
+		t.Fatalf("Expected 2 parked tasks, got %+v, %v", response, err)
+	}
+
+	tests := []struct {
+		name       string
+		timeSlices []int
+		retryAfter bool
+	}{
+		{"Past the limit", []int{2}, true},
+		{"Over the limit alone", []int{2, 2, 2}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.SubmitTasks("team-a", tc.timeSlices)
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) || quotaErr.Reason != ReasonParkedTasks {
+				t.Fatalf("Expected %s to be exceeded, got %v", ReasonParkedTasks, err)
+			}
+			if (quotaErr.RetryAfter > 0) != tc.retryAfter {
+				t.Errorf("Expected retry after: %v, got %v", tc.retryAfter, quotaErr.RetryAfter)
+			}
+		})
+	}
+	if status := service.GetStatus("team-a"); status.ParkedTasks != 2 {
+		t.Errorf("Expected rejected submissions not to be parked, got %d parked tasks", status.ParkedTasks)
+	}
+}
+
+func TestTaskService_InvalidDuration(t *testing.T) {
+	service := NewTaskService(2)
+
+	for _, spec := range []dto.TaskSpec{{Duration: 0}, {Duration: -3}} {
+		if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{spec}); !errors.Is(err, ErrInvalidTask) {
+			t.Errorf("Expected duration %d to be invalid, got %v", spec.Duration, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"sync"
+	"time"
+)
+
+// ErrInvalidAuditQuery is returned for an audit query that cannot be answered.
+var ErrInvalidAuditQuery = errors.New("invalid audit query")
+
+// AuditLog is an append-only log of administrative actions. With a file,
+// every entry is appended to it as a JSON line, and the entries already in
+// it are loaded when it is opened.
+type AuditLog struct {
+	mu      sync.Mutex
+	file    *os.File // nil keeps the entries in memory only
+	entries []models.AuditEntry
+	now     func() time.Time
+}
+
+// NewAuditLog returns an audit log kept in memory.
+func NewAuditLog() *AuditLog {
+	return &AuditLog{now: time.Now}
+}
+
+// OpenAuditLog returns an audit log appending to the file at path. A last
+// entry cut off by a crash is dropped; any other invalid line is an error.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l := &AuditLog{file: file, now: time.Now}
+	err = readJSONLines(file, func(line []byte) error {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		l.entries = append(l.entries, entry)
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("invalid audit log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Close closes the file of the log.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Record appends entry with the next ID and the current time. The action
+// has already happened, so an entry the file cannot take is logged and
+// kept in memory.
+func (l *AuditLog) Record(entry models.AuditEntry) models.AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.ID = len(l.entries) + 1
+	if len(l.entries) > 0 {
+		entry.ID = l.entries[len(l.entries)-1].ID + 1
+	}
+	entry.Time = l.now().UTC()
+	l.entries = append(l.entries, entry)
+	if l.file != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = l.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			log.Printf("Failed to write audit entry %d: %v", entry.ID, err)
+		}
+	}
+	return entry
+}
+
+// RecordStartup records that value is the setting of action at startup,
+// unless it was already the value of the last such entry.
+func (l *AuditLog) RecordStartup(action string, value any) {
+	l.mu.Lock()
+	var old any
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if l.entries[i].Action == action {
+			old = l.entries[i].NewValue
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	// 旧值来自日志文件，按JSON比较
+	oldJSON, _ := json.Marshal(old)
+	newJSON, _ := json.Marshal(value)
+	if old != nil && bytes.Equal(oldJSON, newJSON) {
+		return
+	}
+	l.Record(models.AuditEntry{Actor: "startup", Action: action, OldValue: old, NewValue: value})
+}
+
+// Query returns the entries matching query, oldest first.
+func (l *AuditLog) Query(query dto.AuditQuery) ([]models.AuditEntry, error) {
+	if query.Limit < 0 {
+		return nil, fmt.Errorf("%w: limit cannot be negative", ErrInvalidAuditQuery)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAuditQuery)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]models.AuditEntry, 0)
+	for _, entry := range l.entries {
+		if (!query.From.IsZero() && entry.Time.Before(query.From)) ||
+			(!query.To.IsZero() && !entry.Time.Before(query.To)) ||
+			(query.Actor != "" && entry.Actor != query.Actor) ||
+			(query.Action != "" && entry.Action != query.Action) ||
+			(query.Tenant != "" && entry.Tenant != query.Tenant) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
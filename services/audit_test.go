@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	audit.now = func() time.Time { return now }
+
+	audit.RecordStartup(models.AuditSetBandwidth, 5)
+	audit.Record(models.AuditEntry{Actor: "key:ops", Action: models.AuditSwitchScheduler, Tenant: "team-a",
+		OldValue: map[string]any{"strategy": "FIFO"}, NewValue: map[string]any{"strategy": "SRTF"}})
+	now = start.Add(time.Hour)
+	audit.Record(models.AuditEntry{Actor: "key:ci", Action: models.AuditCancelTask, Tenant: "team-b", Target: "7"})
+	audit.Close()
+	// 追加时崩溃留下的半行
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	file.WriteString(`{"id":4,"action":"sched`)
+	file.Close()
+
+	// 重新打开后丢弃半行、保留已有记录，启动设置未变时不再记录
+	audit, err = OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer audit.Close()
+	audit.now = func() time.Time { return now }
+	audit.RecordStartup(models.AuditSetBandwidth, 5)
+	audit.RecordStartup(models.AuditSetBandwidth, 8)
+
+	tests := []struct {
+		name     string
+		query    dto.AuditQuery
+		expected []int
+	}{
+		{"All", dto.AuditQuery{}, []int{1, 2, 3, 4}},
+		{"Actor", dto.AuditQuery{Actor: "key:ops"}, []int{2}},
+		{"Action and tenant", dto.AuditQuery{Action: models.AuditCancelTask, Tenant: "team-b"}, []int{3}},
+		{"Time range", dto.AuditQuery{From: start, To: start.Add(time.Hour)}, []int{1, 2}},
+		{"Limit", dto.AuditQuery{Limit: 2}, []int{3, 4}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := audit.Query(tc.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(entries) != len(tc.expected) {
+				t.Fatalf("Expected entries %v, got %+v", tc.expected, entries)
+			}
+			for i, entry := range entries {
+				if entry.ID != tc.expected[i] {
+					t.Errorf("Expected entry %d, got %+v", tc.expected[i], entry)
+				}
+			}
+		})
+	}
+
+	entries, _ := audit.Query(dto.AuditQuery{Action: models.AuditSetBandwidth})
+	if len(entries) != 2 || entries[1].OldValue != float64(5) || entries[1].NewValue != 8 {
+		t.Errorf("Expected the bandwidth to change from 5 to 8, got %+v", entries)
+	}
+	if _, err := audit.Query(dto.AuditQuery{Limit: -1}); !errors.Is(err, ErrInvalidAuditQuery) {
+		t.Errorf("Expected ErrInvalidAuditQuery, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(lines) != 4 || !strings.HasSuffix(string(data), "}\n") {
+		t.Errorf("Expected 4 complete lines, got %q", data)
+	}
+
+	// 中间的坏行不是崩溃造成的，不能静默丢弃
+	if err := os.WriteFile(path, []byte("{\"id\":1}\nnot json\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := OpenAuditLog(path); err == nil {
+		t.Error("Expected an error for an invalid line, got nil")
+	}
+}
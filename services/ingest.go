@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ingestThreshold is the largest submission queued directly under the
+	// service lock; larger ones wait in the ingestion queue for the next cycle.
+	ingestThreshold = 1000
+	// streamChunk is how many streamed tasks are handed to the ingestion queue at once.
+	streamChunk = 10000
+	// maxStreamLine bounds the size of one JSON Lines record.
+	maxStreamLine = 1 << 20
+)
+
+// ingestQueue buffers submitted tasks until the next scheduling cycle
+// merges them into the scheduler, so bulk submissions never wait for the
+// service lock.
+type ingestQueue struct {
+	mu      sync.Mutex
+	tasks   []models.Task
+	tenants map[string]int // 每个租户等待合并的任务数
+	count   atomic.Int64
+}
+
+// add queues tasks that all belong to tenant.
+func (q *ingestQueue) add(tenant string, tasks []models.Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks = append(q.tasks, tasks...)
+	if q.tenants == nil {
+		q.tenants = make(map[string]int)
+	}
+	q.tenants[tenant] += len(tasks)
+	q.count.Add(int64(len(tasks)))
+}
+
+func (q *ingestQueue) drain() []models.Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := q.tasks
+	q.tasks = nil
+	q.tenants = nil
+	q.count.Add(-int64(len(tasks)))
+	return tasks
+}
+
+func (q *ingestQueue) len() int {
+	return int(q.count.Load())
+}
+
+func (q *ingestQueue) tenantLen(tenant string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.tenants[tenant]
+}
+
+// mergeIngested moves the tasks waiting in the ingestion queue into the
+// scheduler in one batch. Callers must hold the write lock.
+func (ts *TaskService) mergeIngested() {
+	if tasks := ts.ingest.drain(); len(tasks) > 0 {
+		ts.admit(tasks)
+	}
+}
+
+// StreamError reports a task stream that stopped part way. The tasks read
+// before the failure stay queued as job JobID.
+type StreamError struct {
+	JobID     string
+	TaskCount int // tasks queued before the failure
+	Line      int // the failing line, 0 when reading the stream failed
+	Err       error
+}
+
+func (e *StreamError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %v (%d tasks already queued as job %s)", e.Line, e.Err, e.TaskCount, e.JobID)
+	}
+	return fmt.Sprintf("%v (%d tasks already queued as job %s)", e.Err, e.TaskCount, e.JobID)
+}
+
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// SubmitTaskStream reads one task spec per line from r, either a duration
+// or a task object, and queues them as one job of tenant in chunks through
+// the ingestion queue. Tasks read before an invalid line, or before a chunk
+// the tenant quota rejects, stay queued; the returned StreamError tells how
+// many and where the stream stopped.
+func (ts *TaskService) SubmitTaskStream(tenant string, r io.Reader) (*dto.TaskSubmissionResponse, error) {
+	jobID := uuid.New().String()[:8]
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+
+	count, parked := 0, 0
+	chunk := make([]models.Task, 0, streamChunk)
+	// firstLine is the line of the first task in chunk
+	firstLine := 0
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		admitted, err := ts.admission.reserve(tenant, chunk)
+		if err != nil {
+			return &StreamError{JobID: jobID, TaskCount: count, Line: firstLine, Err: ts.retryAfter(err)}
+		}
+		ts.addJobTasks(jobID, len(chunk))
+		ts.ingest.add(tenant, admitted)
+		count += len(chunk)
+		parked += len(chunk) - len(admitted)
+		chunk = make([]models.Task, 0, streamChunk)
+		return nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var spec dto.TaskSpec
+		var task models.Task
+		err := json.Unmarshal(data, &spec)
+		if err == nil {
+			task, err = ts.newTask(tenant, spec, jobID)
+		}
+		if err != nil {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			return nil, &StreamError{JobID: jobID, TaskCount: count, Line: line, Err: fmt.Errorf("%w: %v", ErrInvalidTask, err)}
+		}
+
+		if len(chunk) == 0 {
+			firstLine = line
+		}
+		chunk = append(chunk, task)
+		if len(chunk) == streamChunk {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &StreamError{JobID: jobID, TaskCount: count, Err: fmt.Errorf("reading task stream: %w", err)}
+	}
+
+	return submissionResponse(jobID, count, parked), nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"scheduler-service/models"
+	"strings"
+	"testing"
+)
+
+func TestTaskService_BulkSubmission(t *testing.T) {
+	service := NewTaskService(5)
+
+	timeSlices := make([]int, ingestThreshold+10)
+	for i := range timeSlices {
+		timeSlices[i] = 3
+	}
+	response, err := service.SubmitTasks(models.DefaultTenant, timeSlices)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 大批量提交先进入摄取队列，下一个周期才合并
+	status := service.GetStatus(models.DefaultTenant)
+	if status.PendingTasks != len(timeSlices) || len(status.ActiveTasks) != 0 {
+		t.Fatalf("Expected %d pending and no active tasks, got %d and %d",
+			len(timeSlices), status.PendingTasks, len(status.ActiveTasks))
+	}
+	if !service.HasActiveTasks() || !service.IsJobActive(response.JobID) {
+		t.Error("Expected pending tasks to count as active")
+	}
+
+	service.ExecuteSchedulingCycle()
+	status = service.GetStatus(models.DefaultTenant)
+	if status.PendingTasks != 0 || status.ActiveCount+len(status.CompletedTasks) != len(timeSlices) {
+		t.Errorf("Expected all %d tasks to be merged, got %d pending, %d active and %d completed",
+			len(timeSlices), status.PendingTasks, status.ActiveCount, len(status.CompletedTasks))
+	}
+	if len(status.ActiveTasks) != statusTaskLimit || !status.ActiveTruncated {
+		t.Errorf("Expected the status to list %d tasks and be truncated, got %d, %t", statusTaskLimit, len(status.ActiveTasks), status.ActiveTruncated)
+	}
+}
+
+func TestTaskService_SubmitTaskStream(t *testing.T) {
+	service := NewTaskService(5)
+
+	stream := "3\n\n{\"duration\": 2, \"priority\": 1}\n4\n"
+	response, err := service.SubmitTaskStream(models.DefaultTenant, strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.TaskCount != 3 {
+		t.Errorf("Expected 3 tasks, got %d", response.TaskCount)
+	}
+
+	service.ExecuteSchedulingCycle()
+	if status := service.GetStatus(models.DefaultTenant); status.ActiveCount+len(status.CompletedTasks) != 3 {
+		t.Errorf("Expected 3 tasks to be merged, got %d active and %d completed",
+			status.ActiveCount, len(status.CompletedTasks))
+	}
+
+	_, err = service.SubmitTaskStream(models.DefaultTenant, strings.NewReader("3\n{\"duration\": 3, \"width\": 99}\n"))
+	if !errors.Is(err, ErrInvalidTask) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an invalid task error on line 2, got %v", err)
+	}
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Line != 2 || streamErr.TaskCount != 1 || streamErr.JobID == "" {
+		t.Fatalf("Expected a stream error on line 2 after 1 queued task, got %#v", err)
+	}
+	if !service.IsJobActive(streamErr.JobID) {
+		t.Errorf("Expected the task before line 2 to stay queued as job %s", streamErr.JobID)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// readJSONLines calls fn with every line of the append-only file, which is
+// left positioned at its end. A last line without its newline is what a
+// crash while appending leaves behind: it is cut off, so the next append
+// starts on a line of its own.
+func readJSONLines(file *os.File, fn func(line []byte) error) error {
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				log.Printf("Dropping the incomplete line %d of %s", line, file.Name())
+				return file.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(data))
+		if data = bytes.TrimSpace(data); len(data) == 0 {
+			continue
+		}
+		if err := fn(data); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
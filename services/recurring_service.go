@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"scheduler-service/cron"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxScheduleRuns bounds the run history kept for each recurring schedule.
+const maxScheduleRuns = 100
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+)
+
+type recurringEntry struct {
+	schedule models.RecurringSchedule
+	cron     *cron.Schedule
+	interval time.Duration
+	tasks    []dto.TaskSpec
+	lastJob  string
+	pending  bool // 上一次运行未结束时排队的一次运行
+}
+
+// RecurringService submits tasks for recurring schedules, replacing external
+// cron jobs that call POST /tasks.
+type RecurringService struct {
+	taskService *TaskService
+	mu          sync.Mutex
+	entries     map[string]*recurringEntry
+	order       []string
+	now         func() time.Time
+	stopChan    chan bool
+	isRunning   bool
+	wg          sync.WaitGroup
+}
+
+func NewRecurringService(taskService *TaskService) *RecurringService {
+	return &RecurringService{
+		taskService: taskService,
+		entries:     make(map[string]*recurringEntry),
+		now:         time.Now,
+		stopChan:    make(chan bool),
+	}
+}
+
+// Create registers a schedule that submits its tasks as jobs of tenant.
+func (rs *RecurringService) Create(tenant string, req dto.RecurringScheduleRequest) (*models.RecurringSchedule, error) {
+	entry := &recurringEntry{tasks: req.Tasks}
+	switch {
+	case req.Cron != "" && req.Interval != "":
+		return nil, fmt.Errorf("%w: cron and interval are mutually exclusive", ErrInvalidSchedule)
+	case req.Cron != "":
+		schedule, err := cron.Parse(req.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+		entry.cron = schedule
+	case req.Interval != "":
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("%w: invalid interval %q", ErrInvalidSchedule, req.Interval)
+		}
+		entry.interval = interval
+	default:
+		return nil, fmt.Errorf("%w: cron or interval is required", ErrInvalidSchedule)
+	}
+
+	if len(req.Tasks) == 0 {
+		return nil, fmt.Errorf("%w: durations cannot be empty", ErrInvalidSchedule)
+	}
+
+	overlap := req.Overlap
+	if overlap == "" {
+		overlap = models.OverlapSkip
+	}
+	if overlap != models.OverlapSkip && overlap != models.OverlapQueue {
+		return nil, fmt.Errorf("%w: overlap must be %s or %s", ErrInvalidSchedule, models.OverlapSkip, models.OverlapQueue)
+	}
+
+	if req.Strategy != "" {
+		if !slices.Contains(rs.taskService.GetAvailableStrategies(), req.Strategy) {
+			return nil, fmt.Errorf("%w: unsupported strategy %s", ErrInvalidSchedule, req.Strategy)
+		}
+		if slices.ContainsFunc(req.Tasks, func(spec dto.TaskSpec) bool { return spec.Group != "" }) {
+			return nil, fmt.Errorf("%w: tasks of a schedule with a strategy run in its own group and cannot set group", ErrInvalidSchedule)
+		}
+	}
+
+	now := rs.now()
+	entry.schedule = models.RecurringSchedule{
+		ID:          uuid.New().String()[:8],
+		Tenant:      tenant,
+		Cron:        req.Cron,
+		Interval:    req.Interval,
+		TaskCount:   len(req.Tasks),
+		Strategy:    req.Strategy,
+		Overlap:     overlap,
+		CreatedTime: now,
+		History:     make([]models.ScheduleRun, 0),
+	}
+	entry.schedule.NextRun = entry.next(now)
+	if req.Strategy != "" {
+		entry.schedule.Group = "schedules/" + entry.schedule.ID
+		if err := rs.setStrategy(entry); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.entries[entry.schedule.ID] = entry
+	rs.order = append(rs.order, entry.schedule.ID)
+	return entry.snapshot(), nil
+}
+
+// setStrategy switches the group of the schedule, or within every partition
+// its tasks are routed to, to the strategy of the schedule.
+func (rs *RecurringService) setStrategy(entry *recurringEntry) error {
+	paths := make(map[string]bool)
+	for _, spec := range entry.specs() {
+		paths[rs.taskService.engine.Route(spec.Labels, spec.Group)] = true
+	}
+	for path := range paths {
+		req := dto.GroupRequest{Path: path, Strategy: entry.schedule.Strategy}
+		if _, _, err := rs.taskService.SetGroup(entry.schedule.Tenant, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the schedules of tenant.
+func (rs *RecurringService) List(tenant string) []models.RecurringSchedule {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	schedules := make([]models.RecurringSchedule, 0, len(rs.order))
+	for _, id := range rs.order {
+		if entry := rs.entries[id]; entry.schedule.Tenant == tenant {
+			schedules = append(schedules, *entry.snapshot())
+		}
+	}
+	return schedules
+}
+
+// entry returns the schedule with the given id if it belongs to tenant.
+// Callers must hold the lock.
+func (rs *RecurringService) entry(tenant, id string) (*recurringEntry, error) {
+	entry, exists := rs.entries[id]
+	if !exists || entry.schedule.Tenant != tenant {
+		return nil, ErrScheduleNotFound
+	}
+	return entry, nil
+}
+
+func (rs *RecurringService) Get(tenant, id string) (*models.RecurringSchedule, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	entry, err := rs.entry(tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	return entry.snapshot(), nil
+}
+
+func (rs *RecurringService) Pause(tenant, id string) (*models.RecurringSchedule, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	entry, err := rs.entry(tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	entry.schedule.Paused = true
+	entry.pending = false
+	return entry.snapshot(), nil
+}
+
+func (rs *RecurringService) Resume(tenant, id string) (*models.RecurringSchedule, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	entry, err := rs.entry(tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.schedule.Paused {
+		entry.schedule.Paused = false
+		entry.schedule.NextRun = entry.next(rs.now())
+	}
+	return entry.snapshot(), nil
+}
+
+func (rs *RecurringService) Delete(tenant, id string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, err := rs.entry(tenant, id); err != nil {
+		return err
+	}
+	delete(rs.entries, id)
+	rs.order = slices.DeleteFunc(rs.order, func(other string) bool { return other == id })
+	return nil
+}
+
+// RunDue submits every schedule whose next run is due at now.
+func (rs *RecurringService) RunDue(now time.Time) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, id := range rs.order {
+		entry := rs.entries[id]
+		if entry.schedule.Paused {
+			continue
+		}
+
+		running := entry.lastJob != "" && rs.taskService.IsJobActive(entry.lastJob)
+		if entry.pending && !running {
+			entry.pending = false
+			rs.submit(entry, now)
+			running = true
+		}
+
+		if now.Before(entry.schedule.NextRun) || entry.schedule.NextRun.IsZero() {
+			continue
+		}
+		entry.schedule.NextRun = entry.next(now)
+
+		switch {
+		case !running:
+			rs.submit(entry, now)
+		case entry.schedule.Overlap == models.OverlapQueue && !entry.pending:
+			entry.pending = true
+			entry.record(models.ScheduleRun{Time: now, Status: models.RunQueued})
+		default:
+			entry.record(models.ScheduleRun{Time: now, Status: models.RunSkipped})
+		}
+	}
+}
+
+// specs returns the tasks of one run, labelled with the schedule and in
+// its group if it has one.
+func (e *recurringEntry) specs() []dto.TaskSpec {
+	specs := make([]dto.TaskSpec, len(e.tasks))
+	for i, spec := range e.tasks {
+		labels := cloneLabels(spec.Labels)
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels["schedule"] = e.schedule.ID
+		spec.Labels = labels
+		if e.schedule.Group != "" {
+			spec.Group = e.schedule.Group
+		}
+		specs[i] = spec
+	}
+	return specs
+}
+
+func (rs *RecurringService) submit(entry *recurringEntry, now time.Time) {
+	response, err := rs.taskService.SubmitTaskSpecs(entry.schedule.Tenant, entry.specs())
+	if err != nil {
+		entry.record(models.ScheduleRun{Time: now, Status: models.RunFailed, Error: err.Error()})
+		return
+	}
+	entry.lastJob = response.JobID
+	entry.record(models.ScheduleRun{Time: now, JobID: response.JobID, Status: models.RunSubmitted})
+}
+
+func (e *recurringEntry) next(now time.Time) time.Time {
+	if e.cron != nil {
+		return e.cron.Next(now)
+	}
+	return now.Add(e.interval)
+}
+
+func (e *recurringEntry) record(run models.ScheduleRun) {
+	e.schedule.History = append(e.schedule.History, run)
+	if len(e.schedule.History) > maxScheduleRuns {
+		e.schedule.History = e.schedule.History[len(e.schedule.History)-maxScheduleRuns:]
+	}
+}
+
+func (e *recurringEntry) snapshot() *models.RecurringSchedule {
+	schedule := e.schedule
+	schedule.History = slices.Clone(e.schedule.History)
+	return &schedule
+}
+
+func (rs *RecurringService) Start() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.isRunning {
+		return
+	}
+
+	rs.isRunning = true
+	rs.wg.Add(1)
+	go rs.run()
+	fmt.Println("Recurring schedule service started")
+}
+
+func (rs *RecurringService) Stop() {
+	rs.mu.Lock()
+	if !rs.isRunning {
+		rs.mu.Unlock()
+		return
+	}
+	rs.isRunning = false
+	rs.mu.Unlock()
+
+	rs.stopChan <- true
+	rs.wg.Wait()
+	fmt.Println("Recurring schedule service stopped")
+}
+
+func (rs *RecurringService) run() {
+	defer rs.wg.Done()
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rs.RunDue(rs.now())
+		case <-rs.stopChan:
+			return
+		}
+	}
+}
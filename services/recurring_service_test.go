@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"scheduler-service/scheduler"
+	"testing"
+	"time"
+)
+
+func newTestRecurringService(now *time.Time) *RecurringService {
+	rs := NewRecurringService(NewTaskService(5))
+	rs.now = func() time.Time { return *now }
+	return rs
+}
+
+func TestRecurringService_CreateValidation(t *testing.T) {
+	now := time.Now()
+	rs := newTestRecurringService(&now)
+	tasks := []dto.TaskSpec{{Duration: 1}}
+
+	invalid := []dto.RecurringScheduleRequest{
+		{Tasks: tasks},
+		{Cron: "* * * * *", Interval: "1m", Tasks: tasks},
+		{Cron: "bad", Tasks: tasks},
+		{Interval: "-1s", Tasks: tasks},
+		{Interval: "1m"},
+		{Interval: "1m", Tasks: tasks, Overlap: "parallel"},
+		{Interval: "1m", Tasks: tasks, Strategy: "INVALID"},
+		{Interval: "1m", Tasks: []dto.TaskSpec{{Duration: 1, Group: "eng"}}, Strategy: "SRTF"},
+	}
+	for _, req := range invalid {
+		if _, err := rs.Create(models.DefaultTenant, req); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Expected ErrInvalidSchedule for %+v, got %v", req, err)
+		}
+	}
+
+	schedule, err := rs.Create(models.DefaultTenant, dto.RecurringScheduleRequest{Interval: "1m", Tasks: tasks})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if schedule.Overlap != models.OverlapSkip {
+		t.Errorf("Expected default overlap to be skip, got %s", schedule.Overlap)
+	}
+	if !schedule.NextRun.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected next run one interval from now, got %v", schedule.NextRun)
+	}
+}
+
+func TestRecurringService_RunDue(t *testing.T) {
+	now := time.Now()
+	rs := newTestRecurringService(&now)
+
+	schedule, err := rs.Create(models.DefaultTenant, dto.RecurringScheduleRequest{
+		Interval: "1m",
+		Tasks:    []dto.TaskSpec{{Duration: 3}},
+		Strategy: "SRTF",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rs.RunDue(now)
+	if got, _ := rs.Get(models.DefaultTenant, schedule.ID); len(got.History) != 0 {
+		t.Fatalf("Expected no run before the interval elapsed, got %v", got.History)
+	}
+
+	now = now.Add(time.Minute)
+	rs.RunDue(now)
+	got, _ := rs.Get(models.DefaultTenant, schedule.ID)
+	if len(got.History) != 1 || got.History[0].Status != models.RunSubmitted {
+		t.Fatalf("Expected one submitted run, got %v", got.History)
+	}
+
+	status := rs.taskService.GetStatus(models.DefaultTenant)
+	if len(status.ActiveTasks) != 1 {
+		t.Fatalf("Expected 1 active task, got %d", len(status.ActiveTasks))
+	}
+	labels := status.ActiveTasks[0].Labels
+	if labels["schedule"] != schedule.ID {
+		t.Errorf("Expected the schedule label, got %v", labels)
+	}
+	// 策略通过计划自己的分组生效
+	if group := status.ActiveTasks[0].Group; group != "schedules/"+schedule.ID || schedule.Group != group {
+		t.Errorf("Expected the task in group schedules/%s, got %q", schedule.ID, group)
+	}
+	for _, group := range status.Groups {
+		if group.Path == schedule.Group && group.Strategy != "SRTF" {
+			t.Errorf("Expected the schedule's group to run SRTF, got %s", group.Strategy)
+		}
+	}
+}
+
+func TestRecurringService_StrategyWithPartitions(t *testing.T) {
+	partitions, _ := scheduler.ParsePartitions("interactive=FIFO:3:tier=interactive,batch=FIFO:2")
+	engine, err := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: 5, Partitions: partitions})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rs := NewRecurringService(NewTaskServiceWithEngine(engine))
+
+	schedule, err := rs.Create(models.DefaultTenant, dto.RecurringScheduleRequest{
+		Interval: "1m",
+		Tasks:    []dto.TaskSpec{{Duration: 3}, {Duration: 3, Labels: map[string]string{"tier": "interactive"}}},
+		Strategy: "SRTF",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	strategies := make(map[string]string)
+	for _, group := range rs.taskService.GetStatus(models.DefaultTenant).Groups {
+		strategies[group.Path] = group.Strategy
+	}
+	for _, partition := range []string{"interactive", "batch"} {
+		if path := partition + "/" + schedule.Group; strategies[path] != "SRTF" {
+			t.Errorf("Expected %s to run SRTF, got %v", path, strategies)
+		}
+	}
+}
+
+func TestRecurringService_Overlap(t *testing.T) {
+	tests := []struct {
+		overlap          string
+		expectedStatuses []string
+	}{
+		{models.OverlapSkip, []string{models.RunSubmitted, models.RunSkipped, models.RunSubmitted}},
+		{models.OverlapQueue, []string{models.RunSubmitted, models.RunQueued, models.RunSubmitted}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.overlap, func(t *testing.T) {
+			now := time.Now()
+			rs := newTestRecurringService(&now)
+			schedule, err := rs.Create(models.DefaultTenant, dto.RecurringScheduleRequest{
+				Interval: "1m",
+				Tasks:    []dto.TaskSpec{{Duration: 5}},
+				Overlap:  tc.overlap,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			now = now.Add(time.Minute)
+			rs.RunDue(now)
+
+			// 上一次运行还没完成
+			now = now.Add(time.Minute)
+			rs.RunDue(now)
+
+			// 上一次运行完成后，排队的运行立即提交，跳过的运行等下一个周期
+			rs.taskService.ExecuteSchedulingCycle()
+			if tc.overlap == models.OverlapSkip {
+				now = now.Add(time.Minute)
+			} else {
+				now = now.Add(time.Second)
+			}
+			rs.RunDue(now)
+
+			got, _ := rs.Get(models.DefaultTenant, schedule.ID)
+			if len(got.History) != len(tc.expectedStatuses) {
+				t.Fatalf("Expected %d runs, got %v", len(tc.expectedStatuses), got.History)
+			}
+			for i, run := range got.History {
+				if run.Status != tc.expectedStatuses[i] {
+					t.Errorf("Run %d: expected status %s, got %s", i, tc.expectedStatuses[i], run.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestRecurringService_PauseResumeDelete(t *testing.T) {
+	now := time.Now()
+	rs := newTestRecurringService(&now)
+	schedule, err := rs.Create(models.DefaultTenant, dto.RecurringScheduleRequest{Interval: "1m", Tasks: []dto.TaskSpec{{Duration: 1}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := rs.Pause(models.DefaultTenant, schedule.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now = now.Add(time.Hour)
+	rs.RunDue(now)
+	if got, _ := rs.Get(models.DefaultTenant, schedule.ID); len(got.History) != 0 || !got.Paused {
+		t.Fatalf("Expected paused schedule not to run, got %+v", got)
+	}
+
+	resumed, err := rs.Resume(models.DefaultTenant, schedule.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resumed.NextRun.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected next run to restart from resume time, got %v", resumed.NextRun)
+	}
+
+	if err := rs.Delete(models.DefaultTenant, schedule.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := rs.Get(models.DefaultTenant, schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected ErrScheduleNotFound after delete, got %v", err)
+	}
+	if len(rs.List(models.DefaultTenant)) != 0 {
+		t.Errorf("Expected no schedules after delete, got %d", len(rs.List(models.DefaultTenant)))
+	}
+}
+
+func TestRecurringService_Tenants(t *testing.T) {
+	now := time.Now()
+	rs := newTestRecurringService(&now)
+
+	schedule, err := rs.Create("team-a", dto.RecurringScheduleRequest{Interval: "1m", Tasks: []dto.TaskSpec{{Duration: 3}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := rs.Get("team-b", schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected another tenant's schedule to be invisible, got %v", err)
+	}
+	if err := rs.Delete("team-b", schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected another tenant not to delete the schedule, got %v", err)
+	}
+	if len(rs.List("team-b")) != 0 || len(rs.List("team-a")) != 1 {
+		t.Error("Expected schedules to be listed only for their tenant")
+	}
+
+	now = now.Add(time.Minute)
+	rs.RunDue(now)
+	if status := rs.taskService.GetStatus("team-a"); len(status.ActiveTasks) != 1 {
+		t.Errorf("Expected the run to submit tasks for team-a, got %d", len(status.ActiveTasks))
+	}
+}
@@ -2,16 +2,28 @@ package services
 
 import (
 	"fmt"
+	"log"
 	"sync"
 	"time"
 )
 
+// cycleInterval is how often the scheduler service runs a scheduling cycle.
+const cycleInterval = time.Second
+
+// predictionSaveInterval is how often the learned runtime model is saved,
+// so that a crash loses at most this much of it.
+const predictionSaveInterval = time.Minute
+
 type SchedulerService struct {
 	taskService *TaskService
 	stopChan    chan bool
 	isRunning   bool
 	wg          sync.WaitGroup
 	mu          sync.RWMutex
+
+	// predictionFile is where the learned runtime model is saved; empty
+	// disables saving.
+	predictionFile string
 }
 
 func NewSchedulerService(taskService *TaskService) *SchedulerService {
@@ -22,6 +34,12 @@ func NewSchedulerService(taskService *TaskService) *SchedulerService {
 	}
 }
 
+// SetPredictionFile saves the learned runtime model to path every
+// predictionSaveInterval while the service runs. Call it before Start.
+func (ss *SchedulerService) SetPredictionFile(path string) {
+	ss.predictionFile = path
+}
+
 func (ss *SchedulerService) Start() {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
@@ -49,8 +67,10 @@ func (ss *SchedulerService) Stop() {
 
 func (ss *SchedulerService) run() {
 	defer ss.wg.Done()
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := time.NewTicker(cycleInterval)
 	defer ticker.Stop()
+	saveTicker := time.NewTicker(predictionSaveInterval)
+	defer saveTicker.Stop()
 
 	for {
 		select {
@@ -59,23 +79,41 @@ func (ss *SchedulerService) run() {
 				ss.taskService.ExecuteSchedulingCycle()
 				ss.printCurrentStatus()
 			}
+		case <-saveTicker.C:
+			ss.savePredictions()
 		case <-ss.stopChan:
 			return
 		}
 	}
 }
 
-func (ss *SchedulerService) printCurrentStatus() {
-	status := ss.taskService.GetStatus()
-	if len(status.ScheduleHistory) == 0 {
+// savePredictions saves the learned runtime model if there is a file for it.
+func (ss *SchedulerService) savePredictions() {
+	if ss.predictionFile == "" {
 		return
 	}
+	if err := ss.taskService.SavePredictions(ss.predictionFile); err != nil {
+		log.Println("Failed to save runtime model: ", err)
+	}
+}
+
+func (ss *SchedulerService) printCurrentStatus() {
+	for _, tenant := range ss.taskService.Tenants() {
+		status := ss.taskService.GetStatus(tenant)
+		if len(status.ScheduleHistory) == 0 {
+			continue
+		}
 
-	latestResult := status.ScheduleHistory[len(status.ScheduleHistory)-1]
-	fmt.Printf("Time: %d, Executed Task Indexes: %v, Remaining Times: %v\n",
-		latestResult.Time,
-		latestResult.TaskIndexes,
-		latestResult.RemainingTimes)
+		latestResult := status.ScheduleHistory[len(status.ScheduleHistory)-1]
+		if latestResult.Time != status.CurrentTime-1 {
+			continue
+		}
+		fmt.Printf("Tenant: %s, Time: %d, Executed Task Indexes: %v, Remaining Times: %v\n",
+			tenant,
+			latestResult.Time,
+			latestResult.TaskIndexes,
+			latestResult.RemainingTimes)
+	}
 }
 
 func (ss *SchedulerService) GracefulStop() {
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"os"
+	"path/filepath"
+	"scheduler-service/dto"
+	"scheduler-service/models"
 	"testing"
 	"time"
 )
@@ -60,7 +64,7 @@ func TestSchedulerService_Run(t *testing.T) {
 
 	// 提交任务
 	timeSlices := []int{3, 5, 2}
-	_, err := taskService.SubmitTasks(timeSlices)
+	_, err := taskService.SubmitTasks(models.DefaultTenant, timeSlices)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -77,7 +81,7 @@ func TestSchedulerService_Run(t *testing.T) {
 	schedulerService.Stop()
 
 	// 验证任务状态
-	status := taskService.GetStatus()
+	status := taskService.GetStatus(models.DefaultTenant)
 
 	// 验证当前时间大于0（表示调度器已经运行）
 	if status.CurrentTime <= 0 {
@@ -95,3 +99,26 @@ func TestSchedulerService_Run(t *testing.T) {
 		t.Errorf("Expected %d total tasks, got %d", len(timeSlices), totalTasks)
 	}
 }
+
+func TestSchedulerService_SavePredictions(t *testing.T) {
+	taskService := NewTaskService(5)
+	schedulerService := NewSchedulerService(taskService)
+	path := filepath.Join(t.TempDir(), "model.json")
+	schedulerService.SetPredictionFile(path)
+
+	if _, err := taskService.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 1, Labels: map[string]string{"job": "etl"}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taskService.ExecuteSchedulingCycle()
+	schedulerService.savePredictions()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the model to be saved: %v", err)
+	}
+
+	// 没有新学到的任务时不重写文件
+	os.Remove(path)
+	schedulerService.savePredictions()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no save without new completions, got %v", err)
+	}
+}
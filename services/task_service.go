@@ -1,151 +1,1129 @@
 package services
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/rand"
 	"scheduler-service/dto"
 	"scheduler-service/models"
+	"scheduler-service/predict"
 	"scheduler-service/scheduler"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrInvalidTask is returned when a submitted task can never be scheduled.
+	ErrInvalidTask = errors.New("invalid task")
+	// ErrTaskNotFound is returned when no queued or delayed task has the given index.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrPartitioned is returned when switching the strategy of a tenant
+	// whose tasks all go to partitions with strategies of their own.
+	ErrPartitioned = errors.New("tenants are partitioned, set the strategy of a partition with /groups")
+)
+
 type TaskService struct {
 	mu sync.RWMutex
 	// tasks            []*models.Task
-	completedTasks   []*models.Task
-	scheduleHistory  []models.ScheduleResult
-	schedulerManager *scheduler.SchedulerManager
-	bandwidth        int
-	currentTime      int
+	engine           *scheduler.Engine
+	tenants          map[string]*tenantState
+	jobsMu           sync.Mutex
+	activeJobs       map[string]int // 每个作业未完成的任务数
+	ingest           ingestQueue
+	admission        *admission
+	usage            *usageLedger
+	capacity         atomic.Pointer[models.Resources]
+	bandwidth        atomic.Int64 // 提交时无需服务锁即可检查宽度
 	isRunning        bool
+	rng              *rand.Rand
+	metrics          models.Metrics
+	estimateErrorSum int
+	predictMu        sync.RWMutex // 提交时无需服务锁即可预测运行时间
+	predictions      *predict.Model
+	learned          uint64        // 模型学到的完成任务数
+	savedLearned     atomic.Uint64 // 上次保存时的learned
+
+	// 每次状态变化后发布的只读快照，读取方无需加锁
+	snapshot atomic.Pointer[statusSnapshot]
+	version  uint64
+	statuses sync.Map        // 租户 → 最近发布的*dto.StatusResponse
+	changed  map[string]bool // 自上次发布后状态变化的租户
+	running  []string        // 上个周期有任务运行的租户
+}
+
+// tenantState holds the records of one tenant.
+type tenantState struct {
+	delayQueue      *scheduler.DelayQueue
+	completedTasks  []models.Task
+	deadTasks       []*models.Task
+	scheduleHistory []models.ScheduleResult
 }
 
+// statusSnapshot is the immutable state published after every change. The
+// tenant statuses are published next to it in TaskService.statuses. Their
+// slices share backing arrays with the service and must not be modified.
+type statusSnapshot struct {
+	version  uint64
+	strategy string // 新租户的初始策略
+	metrics  models.Metrics
+}
+
+// NewTaskService serves a default engine with bandwidth units per cycle.
+// bandwidth must be positive, NewTaskService panics otherwise; callers with
+// a configured bandwidth build the engine with scheduler.NewEngine, which
+// returns the error, and use NewTaskServiceWithEngine.
 func NewTaskService(bandwidth int) *TaskService {
-	return &TaskService{
+	engine, err := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: bandwidth})
+	if err != nil {
+		panic(fmt.Sprintf("NewTaskService: %v", err))
+	}
+	return NewTaskServiceWithEngine(engine)
+}
+
+// NewTaskServiceWithEngine serves the tasks of engine, which must not be
+// shared with another service.
+func NewTaskServiceWithEngine(engine *scheduler.Engine) *TaskService {
+	ts := &TaskService{
 		// tasks:            make([]*models.Task, 0),
-		completedTasks:   make([]*models.Task, 0),
-		scheduleHistory:  make([]models.ScheduleResult, 0),
-		schedulerManager: scheduler.NewSchedulerManager(),
-		bandwidth:        bandwidth,
-		currentTime:      0,
-		isRunning:        false,
+		engine:     engine,
+		tenants:    make(map[string]*tenantState),
+		activeJobs: make(map[string]int),
+		changed:    make(map[string]bool),
+		admission:  newAdmission(),
+		usage:      newUsageLedger(),
+		isRunning:  false,
+		rng:        rand.New(rand.NewSource(1)),
+	}
+	ts.bandwidth.Store(int64(engine.Bandwidth()))
+	ts.predictions, _ = predict.New(predict.Mean)
+	ts.engine.SetFailureModel(ts.failureModel)
+	ts.engine.SetEstimateModel(ts.reestimate)
+	ts.publish()
+	return ts
+}
+
+// tenant returns the records of the named tenant, creating them on first
+// use, and marks its status for the next publish. Callers must hold the
+// write lock.
+func (ts *TaskService) tenant(name string) *tenantState {
+	ts.changed[name] = true
+	state, exists := ts.tenants[name]
+	if !exists {
+		state = &tenantState{
+			delayQueue:      scheduler.NewDelayQueue(),
+			completedTasks:  make([]models.Task, 0),
+			deadTasks:       make([]*models.Task, 0),
+			scheduleHistory: make([]models.ScheduleResult, 0),
+		}
+		ts.tenants[name] = state
+		ts.engine.Tenant(name)
+	}
+	return state
+}
+
+// Tenants returns the names of the tenants that have submitted tasks.
+func (ts *TaskService) Tenants() []string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.engine.Tenants()
+}
+
+// SetTenantWeight sets the weight the tenant policy gives the tenant.
+func (ts *TaskService) SetTenantWeight(tenant string, weight int) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.engine.SetTenantWeight(tenant, weight); err != nil {
+		return err
 	}
+	ts.tenant(tenant)
+	ts.publish()
+	return nil
 }
 
-func (ts *TaskService) SubmitTasks(timeSlices []int) (*dto.TaskSubmissionResponse, error) {
+// SetTenantBurst gives tenant burst credits to go above its fair share.
+func (ts *TaskService) SetTenantBurst(tenant string, config scheduler.TokenBucket) error {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	// ts.schedulerManager.GetCurrentScheduler().AddTasks(timeSlices)
+	if err := ts.engine.SetTenantBurst(tenant, config); err != nil {
+		return err
+	}
+	ts.tenant(tenant)
+	ts.publish()
+	return nil
+}
 
-	// // startIndex := len(ts.tasks) + len(ts.completedTasks)
-	for _, timeSlice := range timeSlices {
-		// task := models.NewTask(startIndex+i, timeSlice)
-		task := models.NewTask(timeSlice)
-		// ts.tasks = append(ts.tasks, task)
-		ts.schedulerManager.GetCurrentScheduler().AddTasks(*task)
+func (ts *TaskService) SubmitTasks(tenant string, timeSlices []int) (*dto.TaskSubmissionResponse, error) {
+	specs := make([]dto.TaskSpec, len(timeSlices))
+	for i, timeSlice := range timeSlices {
+		specs[i] = dto.TaskSpec{Duration: timeSlice}
 	}
+	return ts.SubmitTaskSpecs(tenant, specs)
+}
 
+// SubmitTaskSpecs submits specs as one job of tenant. Batches larger than
+// ingestThreshold go through the ingestion queue and reach the scheduler
+// at the next cycle; smaller ones are queued right away. Tasks over the
+// tenant's quota are parked, or the submission fails with a QuotaError.
+func (ts *TaskService) SubmitTaskSpecs(tenant string, specs []dto.TaskSpec) (*dto.TaskSubmissionResponse, error) {
 	jobID := uuid.New().String()[:8]
+	tasks := make([]models.Task, len(specs))
+	for i, spec := range specs {
+		task, err := ts.newTask(tenant, spec, jobID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: task %d %v", ErrInvalidTask, i, err)
+		}
+		tasks[i] = task
+	}
+
+	admitted, err := ts.admission.reserve(tenant, tasks)
+	if err != nil {
+		return nil, ts.retryAfter(err)
+	}
+	ts.addJobTasks(jobID, len(tasks))
+	ts.queue(tenant, admitted)
+
+	return submissionResponse(jobID, len(tasks), len(tasks)-len(admitted)), nil
+}
+
+// queue hands admitted tasks of tenant to the scheduler, through the
+// ingestion queue if there are many of them.
+func (ts *TaskService) queue(tenant string, tasks []models.Task) {
+	if len(tasks) > ingestThreshold {
+		ts.ingest.add(tenant, tasks)
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.admit(tasks)
+	ts.publish()
+}
+
+func submissionResponse(jobID string, count, parked int) *dto.TaskSubmissionResponse {
+	message := "Task submitted successfully"
+	if parked > 0 {
+		message = fmt.Sprintf("Task submitted, %d tasks wait for admission within the tenant quota", parked)
+	}
 	return &dto.TaskSubmissionResponse{
-		JobID:     jobID,
-		Message:   "Task submitted successfully",
-		TaskCount: len(timeSlices),
-	}, nil
+		JobID:       jobID,
+		Message:     message,
+		TaskCount:   count,
+		ParkedTasks: parked,
+	}
+}
+
+// validateSpec reports why a task could never be scheduled.
+func (ts *TaskService) validateSpec(spec dto.TaskSpec) error {
+	if spec.Duration < 0 {
+		return fmt.Errorf("duration cannot be negative")
+	}
+	if _, err := scheduler.ParseGroupPath(spec.Group); err != nil {
+		return err
+	}
+	if _, err := ts.engine.Class(spec.Class); err != nil {
+		return err
+	}
+	for name, units := range spec.Resources {
+		if units < 0 {
+			return fmt.Errorf("resource %s cannot be negative: %d", name, units)
+		}
+	}
+	capacity := ts.getCapacity()
+	if !spec.Resources.Fits(capacity) {
+		return fmt.Errorf("requests %v, capacity is %v", spec.Resources, capacity)
+	}
+	if spec.Width < 0 || spec.Width > ts.getBandwidth() {
+		return fmt.Errorf("width %d must be between 0 and bandwidth %d", spec.Width, ts.getBandwidth())
+	}
+	if err := ts.validateParallelism(spec); err != nil {
+		return err
+	}
+	if spec.Estimate < 0 {
+		return fmt.Errorf("estimate cannot be negative")
+	}
+	if spec.OnUnderestimate != "" && spec.OnUnderestimate != models.UnderestimateExtend && spec.OnUnderestimate != models.UnderestimateKill {
+		return fmt.Errorf("on_underestimate must be %s or %s", models.UnderestimateExtend, models.UnderestimateKill)
+	}
+	if spec.Deadline < 0 {
+		return fmt.Errorf("deadline cannot be negative")
+	}
+	if spec.NotBefore < 0 {
+		return fmt.Errorf("not_before cannot be negative")
+	}
+	if spec.FailureProbability < 0 || spec.FailureProbability > 1 {
+		return fmt.Errorf("failure_probability must be between 0 and 1")
+	}
+	return spec.Retry.Validate()
+}
+
+// newTask validates spec and builds its task, predicting the runtime of a
+// task submitted without an estimate.
+func (ts *TaskService) newTask(tenant string, spec dto.TaskSpec, jobID string) (models.Task, error) {
+	if err := ts.validateSpec(spec); err != nil {
+		return models.Task{}, err
+	}
+	task := ts.engine.NewTask(spec.Duration)
+	task.JobID = jobID
+	task.Tenant = tenant
+	task.Group = ts.engine.Route(spec.Labels, spec.Group)
+	task.Class, _ = ts.engine.Class(spec.Class)
+	task.Labels = cloneLabels(spec.Labels)
+	task.Resources = spec.Resources.Clone()
+	task.Width = spec.Width
+	task.Estimate = spec.Estimate
+	task.OnUnderestimate = spec.OnUnderestimate
+	task.MinParallelism = spec.MinParallelism
+	task.MaxParallelism = spec.MaxParallelism
+	task.Speedup = spec.Speedup
+	task.Priority = spec.Priority
+	task.Deadline = spec.Deadline
+	task.NotBefore = spec.NotBefore
+	task.StartAt = spec.StartAt
+	task.FailureProbability = spec.FailureProbability
+	task.FailureScript = spec.FailureScript
+	task.Retry = spec.Retry
+	if task.Estimate == 0 {
+		ts.predictEstimate(task)
+	}
+	if task.Duration == 0 {
+		return models.Task{}, fmt.Errorf("duration must be positive, or labels must have runtime history")
+	}
+	return *task, nil
+}
+
+// admit hands tasks to the delay queue or the current scheduler of their
+// group. Callers must hold the write lock.
+func (ts *TaskService) admit(tasks []models.Task) {
+	type groupKey struct{ class, tenant, group string }
+	ready := make(map[groupKey][]models.Task)
+	for _, task := range tasks {
+		state := ts.tenant(task.Tenant)
+		if scheduler.IsDelayed(task, ts.engine.CurrentTime(), ts.engine.Now()) {
+			state.delayQueue.Add(task)
+			continue
+		}
+		key := groupKey{task.Class, task.Tenant, task.Group}
+		ready[key] = append(ready[key], task)
+	}
+	for key, tasks := range ready {
+		ts.engine.ClassGroup(key.class, key.tenant, key.group).GetCurrentScheduler().AddBatch(tasks)
+	}
+}
+
+// queues returns the current schedulers of all groups of tenant. Callers
+// must hold the lock.
+func (ts *TaskService) queues(tenant string) []scheduler.Scheduler {
+	groups := ts.engine.Groups(tenant)
+	queues := make([]scheduler.Scheduler, len(groups))
+	for i, group := range groups {
+		queues[i] = group.Manager.GetCurrentScheduler()
+	}
+	return queues
 }
 
-func (ts *TaskService) GetStatus() *dto.StatusResponse {
+// UpdateTask changes the priority, deadline or labels of a queued or
+// delayed task of tenant in place, and returns the task before and after.
+func (ts *TaskService) UpdateTask(tenant string, index int, req dto.TaskUpdateRequest) (previous, task *models.Task, err error) {
+	if req.Deadline != nil && *req.Deadline < 0 {
+		return nil, nil, fmt.Errorf("%w: deadline cannot be negative", ErrInvalidTask)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.mergeIngested()
+	return ts.updateTask(tenant, index, func(task *models.Task) {
+		if req.Priority != nil {
+			task.Priority = *req.Priority
+		}
+		if req.Deadline != nil {
+			task.Deadline = *req.Deadline
+		}
+		if req.Labels != nil {
+			task.Labels = cloneLabels(req.Labels)
+		}
+	})
+}
+
+// MoveTask moves a queued task of tenant to the front of its group's queue,
+// or immediately ahead of another task by taking its priority and a rank
+// between it and the task before it, and returns the task before and after.
+func (ts *TaskService) MoveTask(tenant string, index int, req dto.TaskMoveRequest) (previous, task *models.Task, err error) {
+	if req.Front == (req.Before != nil) {
+		return nil, nil, fmt.Errorf("%w: exactly one of front and before is required", ErrInvalidTask)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.mergeIngested()
+	state := ts.tenant(tenant)
+	class, group := ts.taskGroup(tenant, index)
+	queued := ts.engine.ClassGroup(class, tenant, group).GetCurrentScheduler().Tasks()
+	for _, task := range state.delayQueue.Tasks() {
+		if task.Class == class && task.Group == group {
+			queued = append(queued, task)
+		}
+	}
+	queued = slices.DeleteFunc(queued, func(task models.Task) bool { return task.Index == index })
+	if req.Before == nil {
+		if len(queued) == 0 {
+			// 队列中没有其他任务，已经在最前面
+			return ts.updateTask(tenant, index, func(task *models.Task) {})
+		}
+		first := slices.MinFunc(queued, func(a, b models.Task) int {
+			return cmp.Or(cmp.Compare(b.Priority, a.Priority), cmp.Compare(a.Rank, b.Rank))
+		})
+		return ts.updateTask(tenant, index, func(task *models.Task) {
+			// 优先级更高的任务已经在最前面，不降低它的优先级
+			if task.Priority <= first.Priority {
+				task.Priority = first.Priority
+				task.Rank = first.Rank - 1
+			}
+		})
+	}
+
+	at := slices.IndexFunc(queued, func(task models.Task) bool { return task.Index == *req.Before })
+	if at < 0 {
+		return nil, nil, fmt.Errorf("%w: %d", ErrTaskNotFound, *req.Before)
+	}
+	target := queued[at]
+	// 同优先级中排在目标及其之后的任务后移两位，让出目标之前的一个名次，
+	// 同名次时由策略决定的先后次序不变
+	for i, task := range queued {
+		if task.Priority == target.Priority && (task.Rank > target.Rank || (task.Rank == target.Rank && i >= at)) {
+			ts.applyTask(tenant, task.Index, func(task *models.Task) { task.Rank += 2 })
+		}
+	}
+	return ts.updateTask(tenant, index, func(task *models.Task) {
+		task.Priority = target.Priority
+		task.Rank = target.Rank + 1
+	})
+}
+
+// CancelTask removes a queued or delayed task of tenant, which then no
+// longer keeps its job active.
+func (ts *TaskService) CancelTask(tenant string, index int) (*models.Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.mergeIngested()
+	state := ts.tenant(tenant)
+	var task models.Task
+	ok := false
+	for _, queue := range ts.queues(tenant) {
+		if task, ok = queue.RemoveTask(index); ok {
+			break
+		}
+	}
+	if !ok {
+		task, ok = state.delayQueue.Remove(index)
+	}
+	if ok {
+		ts.admission.update(tenant, -1, -task.RemainingTime)
+	} else {
+		task, ok = ts.admission.cancel(tenant, index)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrTaskNotFound, index)
+	}
+	ts.finishJobTask(task.JobID)
+	ts.metrics.CancelledTasks++
+	ts.publish()
+	return &task, nil
+}
+
+// taskGroup returns the class and group of a queued or delayed task of tenant.
+func (ts *TaskService) taskGroup(tenant string, index int) (string, string) {
+	var class, group string
+	read := func(task *models.Task) { class, group = task.Class, task.Group }
+	for _, queue := range ts.queues(tenant) {
+		if queue.UpdateTask(index, read) {
+			return class, group
+		}
+	}
+	ts.tenant(tenant).delayQueue.Update(index, read)
+	return class, group
+}
+
+// updateTask applies update to a queued or delayed task of tenant and
+// returns copies of the task before and after it.
+func (ts *TaskService) updateTask(tenant string, index int, update func(task *models.Task)) (*models.Task, *models.Task, error) {
+	var previous, updated models.Task
+	apply := func(task *models.Task) {
+		previous = *task
+		update(task)
+		updated = *task
+	}
+	if !ts.applyTask(tenant, index, apply) {
+		return nil, nil, fmt.Errorf("%w: %d", ErrTaskNotFound, index)
+	}
+	ts.publish()
+	return &previous, &updated, nil
+}
+
+// applyTask calls fn for the queued or delayed task of tenant with index,
+// and reports whether there is one.
+func (ts *TaskService) applyTask(tenant string, index int, fn func(task *models.Task)) bool {
+	state := ts.tenant(tenant)
+	found := slices.ContainsFunc(ts.queues(tenant), func(queue scheduler.Scheduler) bool {
+		return queue.UpdateTask(index, fn)
+	})
+	return found || state.delayQueue.Update(index, fn)
+}
+
+// GetStatus returns the tenant's part of the latest published snapshot
+// without taking the lock, so polling readers never delay scheduling cycles
+// or submissions.
+func (ts *TaskService) GetStatus(tenant string) *dto.StatusResponse {
+	// 先取租户状态再取快照，版本号不会比状态旧
+	published, exists := ts.statuses.Load(tenant)
+	snapshot := ts.snapshot.Load()
+	var status dto.StatusResponse
+	if exists {
+		status = *published.(*dto.StatusResponse)
+	} else {
+		// 尚未提交过任务的租户看到空状态
+		status = dto.StatusResponse{
+			Tenant:          tenant,
+			ScheduleHistory: make([]models.ScheduleResult, 0),
+			ActiveTasks:     make([]models.Task, 0),
+			ScheduledTasks:  make([]models.Task, 0),
+			CompletedTasks:  make([]models.Task, 0),
+			CurrentStrategy: snapshot.strategy,
+			Preemptive:      true,
+		}
+	}
+	// 每个周期都变化的字段在读取时填入，发布时只需重建变化的租户
+	status.Version = snapshot.version
+	status.CurrentTime = snapshot.metrics.CurrentTime
+	if credits, ok := snapshot.metrics.BurstCredits[tenant]; ok {
+		status.BurstCredits = &credits
+	}
+	status.PendingTasks = ts.ingest.tenantLen(tenant)
+	status.ParkedTasks = ts.admission.parkedLen(tenant)
+	return &status
+}
+
+// statusTaskLimit bounds how many queued tasks a status snapshot lists, so
+// publishing stays cheap with very long queues.
+const statusTaskLimit = 1000
+
+// publish stores a new snapshot of the state, rebuilding the statuses of
+// the tenants that changed since the last one. Callers must hold the write
+// lock.
+func (ts *TaskService) publish() {
+	ts.version++
+
+	metrics := ts.metrics
+	metrics.CurrentTime = ts.engine.CurrentTime()
+	if metrics.EstimatedTasks > 0 {
+		metrics.EstimateError = float64(ts.estimateErrorSum) / float64(metrics.EstimatedTasks)
+	}
+
+	for name := range ts.changed {
+		status := ts.tenantStatus(name, ts.tenants[name])
+		ts.statuses.Store(name, &status)
+	}
+	clear(ts.changed)
+	metrics.BurstCredits, metrics.BurstBandwidth = ts.engine.BurstCredits()
+	metrics.Classes = ts.classMetrics()
+
+	ts.snapshot.Store(&statusSnapshot{
+		version:  ts.version,
+		strategy: ts.engine.DefaultStrategy(),
+		metrics:  metrics,
+	})
+}
+
+// classMetrics reports the bandwidth of every service class. Callers must hold the lock.
+func (ts *TaskService) classMetrics() []models.ClassMetrics {
+	stats := ts.engine.ClassStats()
+	if stats == nil {
+		return nil
+	}
+	classes := make([]models.ClassMetrics, len(stats))
+	for i, s := range stats {
+		classes[i] = models.ClassMetrics{
+			Name:               s.Name,
+			AllocatedBandwidth: s.Allocated,
+			ActiveCycles:       s.ActiveCycles,
+			HonoredCycles:      s.HonoredCycles,
+		}
+		if cycles := ts.engine.CurrentTime(); cycles > 0 {
+			classes[i].Utilization = float64(s.Allocated) / float64(cycles*ts.engine.Bandwidth())
+		}
+		if s.ActiveCycles > 0 {
+			classes[i].HonoredRate = float64(s.HonoredCycles) / float64(s.ActiveCycles)
+		}
+	}
+	return classes
+}
+
+// tenantStatus builds the status of one tenant. Callers must hold the write lock.
+func (ts *TaskService) tenantStatus(name string, state *tenantState) dto.StatusResponse {
+	groups := ts.engine.Groups(name)
+	current := ts.engine.Tenant(name).GetCurrentScheduler()
+	active := make([]models.Task, 0)
+	count := 0
+	groupStatuses := make([]dto.GroupStatus, len(groups))
+	for i, group := range groups {
+		queue := group.Manager.GetCurrentScheduler()
+		active = append(active, queue.Head(statusTaskLimit-len(active))...)
+		count += queue.GetTasksLen()
+		groupStatuses[i] = newGroupStatus(group)
+	}
+	return dto.StatusResponse{
+		Tenant:          name,
+		ScheduleHistory: state.scheduleHistory[:len(state.scheduleHistory):len(state.scheduleHistory)],
+		ActiveTasks:     active,
+		ActiveCount:     count,
+		ActiveTruncated: len(active) < count,
+		ScheduledTasks:  state.delayQueue.Tasks(),
+		CompletedTasks:  state.completedTasks[:len(state.completedTasks):len(state.completedTasks)],
+		CurrentStrategy: current.GetName(),
+		Preemptive:      current.IsPreemptive(),
+		Groups:          groupStatuses,
+	}
+}
+
+// newGroupStatus reports the settings and own queue of group.
+func newGroupStatus(group scheduler.GroupInfo) dto.GroupStatus {
+	queue := group.Manager.GetCurrentScheduler()
+	return dto.GroupStatus{
+		Class:       group.Class,
+		Path:        group.Path,
+		Weight:      group.Weight,
+		Policy:      group.Policy,
+		Strategy:    queue.GetName(),
+		Preemptive:  queue.IsPreemptive(),
+		QueuedTasks: queue.GetTasksLen(),
+	}
+}
+
+func (ts *TaskService) validateParallelism(spec dto.TaskSpec) error {
+	if spec.Width > 0 && (spec.MinParallelism > 0 || spec.MaxParallelism > 0 || spec.Speedup != nil) {
+		return fmt.Errorf("width cannot be combined with parallelism or speedup")
+	}
+	if spec.MinParallelism < 0 || spec.MaxParallelism < 0 {
+		return fmt.Errorf("parallelism cannot be negative")
+	}
+	if spec.MinParallelism > ts.getBandwidth() {
+		return fmt.Errorf("min_parallelism %d exceeds bandwidth %d", spec.MinParallelism, ts.getBandwidth())
+	}
+	if spec.MaxParallelism > 0 && spec.MinParallelism > spec.MaxParallelism {
+		return fmt.Errorf("min_parallelism %d exceeds max_parallelism %d", spec.MinParallelism, spec.MaxParallelism)
+	}
+	return spec.Speedup.Validate()
+}
+
+// SetSeed reseeds the random generator used to simulate task failures.
+func (ts *TaskService) SetSeed(seed int64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetPredictionModel replaces the model that predicts the runtime of tasks
+// submitted without an estimate.
+func (ts *TaskService) SetPredictionModel(model *predict.Model) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.predictMu.Lock()
+	defer ts.predictMu.Unlock()
+
+	ts.predictions = model
+}
+
+// SavePredictions writes the learned runtime model to path, unless it has
+// learned nothing since it was last saved.
+func (ts *TaskService) SavePredictions(path string) error {
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
 
-	return &dto.StatusResponse{
-		CurrentTime:     ts.currentTime,
-		ScheduleHistory: ts.scheduleHistory,
-		ActiveTasks:     ts.getActiveTasksCopy(),
-		CompletedTasks:  ts.getCompletedTasksCopy(),
-		CurrentStrategy: ts.schedulerManager.GetCurrentScheduler().GetName(),
+	if ts.savedLearned.Load() == ts.learned {
+		return nil
+	}
+	if err := ts.predictions.Save(path); err != nil {
+		return err
+	}
+	ts.savedLearned.Store(ts.learned)
+	return nil
+}
+
+// SetQuotas replaces the quotas of all tenants. Tasks already queued stay queued.
+func (ts *TaskService) SetQuotas(config models.QuotaConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
 	}
+	ts.admission.setConfig(config)
+	return nil
 }
 
-func (ts *TaskService) SwitchScheduler(strategy string) error {
+// SetQuota replaces the quota of tenant and returns the previous one. Tasks
+// already queued stay queued.
+func (ts *TaskService) SetQuota(tenant string, quota models.Quota) (models.Quota, error) {
+	if err := quota.Validate(); err != nil {
+		return models.Quota{}, err
+	}
+	return ts.admission.setQuota(tenant, quota), nil
+}
+
+// SetBandwidth changes the units of every following cycle and returns the
+// previous bandwidth. Besides what the engine checks, a delayed or parked
+// task that would no longer fit refuses the change.
+func (ts *TaskService) SetBandwidth(bandwidth int) (int, error) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	return ts.schedulerManager.SwitchScheduler(strategy)
+	ts.mergeIngested()
+	waiting := ts.admission.parkedTasks()
+	for _, state := range ts.tenants {
+		waiting = append(waiting, state.delayQueue.Tasks()...)
+	}
+	for _, task := range waiting {
+		if need := max(task.Width, task.MinParallelism); need > bandwidth {
+			return 0, fmt.Errorf("task %d needs %d units, more than bandwidth %d", task.Index, need, bandwidth)
+		}
+	}
+	previous := ts.engine.Bandwidth()
+	if err := ts.engine.SetBandwidth(bandwidth); err != nil {
+		return 0, err
+	}
+	ts.bandwidth.Store(int64(bandwidth))
+	ts.publish()
+	return previous, nil
+}
+
+// GetBandwidth returns the units of one cycle.
+func (ts *TaskService) GetBandwidth() int {
+	return ts.getBandwidth()
 }
 
-func (ts *TaskService) ExecuteSchedulingCycle() {
+// getBandwidth reads the bandwidth without taking the service lock.
+func (ts *TaskService) getBandwidth() int {
+	return int(ts.bandwidth.Load())
+}
+
+// OpenUsageFile loads the usage recorded in the file at path, so reports
+// cover the time before a restart, and appends the usage of every minute
+// to it once the minute is over.
+func (ts *TaskService) OpenUsageFile(path string) error {
+	return ts.usage.open(path)
+}
+
+// CloseUsageFile writes the usage of the current minute and closes the file.
+func (ts *TaskService) CloseUsageFile() error {
+	return ts.usage.close()
+}
+
+// SetUnitPrices sets the prices usage reports charge.
+func (ts *TaskService) SetUnitPrices(prices models.UnitPrices) error {
+	if err := prices.Validate(); err != nil {
+		return err
+	}
+	ts.usage.setPrices(prices)
+	return nil
+}
+
+// GetUsage reports the bandwidth-ticks recorded in the range of query and
+// their cost, without waiting for a scheduling cycle.
+func (ts *TaskService) GetUsage(query dto.UsageQuery) (*dto.UsageResponse, error) {
+	return ts.usage.report(query)
+}
+
+// GetQuota returns the quota of tenant and what currently counts against it.
+func (ts *TaskService) GetQuota(tenant string) *dto.QuotaResponse {
+	quota, usage, parked := ts.admission.status(tenant)
+	return &dto.QuotaResponse{
+		Tenant:          tenant,
+		Quota:           quota,
+		QueuedTasks:     usage.queued,
+		OutstandingWork: usage.work,
+		ParkedTasks:     parked,
+	}
+}
+
+// SetCapacity sets the resource capacity shared by the tasks of one cycle.
+func (ts *TaskService) SetCapacity(capacity models.Resources) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 
-	if ts.schedulerManager.GetCurrentScheduler().GetTasksLen() == 0 {
-		return
+	ts.engine.SetCapacity(capacity)
+	ts.capacity.Store(&capacity)
+}
+
+// getCapacity reads the capacity without taking the service lock.
+func (ts *TaskService) getCapacity() models.Resources {
+	if capacity := ts.capacity.Load(); capacity != nil {
+		return *capacity
 	}
+	return nil
+}
 
-	scheduler := ts.schedulerManager.GetCurrentScheduler()
-	scheduledTasks := scheduler.Schedule(ts.bandwidth)
+// SetContextSwitch sets the bandwidth lost when a task joins the running set.
+func (ts *TaskService) SetContextSwitch(cost int, mode string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.engine.SetContextSwitch(cost, mode)
+}
 
-	if len(scheduledTasks) > 0 {
-		var indexes []int
-		var remainingTimes []int
+// SwitchScheduler switches the strategy of tenant; other tenants keep theirs.
+// With partitions the tenant's own queue never gets tasks, so switching it
+// is refused with ErrPartitioned.
+func (ts *TaskService) SwitchScheduler(tenant, strategy string) error {
+	_, _, err := ts.UpdateScheduler(tenant, dto.SchedulerSwitchRequest{Strategy: strategy})
+	return err
+}
 
-		for _, task := range scheduledTasks {
-			indexes = append(indexes, task.Index)
-			remainingTimes = append(remainingTimes, task.RemainingTime)
+// UpdateScheduler sets the preemption mode of req.Strategy, if given, and
+// switches tenant to it. It returns the tenant's strategy and mode before
+// and after, read under the same lock; they differ when an error left the
+// change half done.
+func (ts *TaskService) UpdateScheduler(tenant string, req dto.SchedulerSwitchRequest) (previous, current dto.SchedulerState, err error) {
+	if ts.engine.Partitioned() {
+		return previous, current, ErrPartitioned
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.tenant(tenant)
+	previous = ts.schedulerState(tenant)
+	for _, manager := range ts.engine.GroupManagers(tenant, "") {
+		if req.Preemptive != nil {
+			if err = manager.SetPreemptive(req.Strategy, *req.Preemptive); err != nil {
+				break
+			}
 		}
+		if err = manager.SwitchScheduler(req.Strategy); err != nil {
+			break
+		}
+	}
+	current = ts.schedulerState(tenant)
+	ts.publish()
+	return previous, current, err
+}
+
+// schedulerState returns the strategy of tenant's own queue.
+func (ts *TaskService) schedulerState(tenant string) dto.SchedulerState {
+	current := ts.engine.Tenant(tenant).GetCurrentScheduler()
+	return dto.SchedulerState{Strategy: current.GetName(), Preemptive: current.IsPreemptive()}
+}
+
+// SetGroup creates or changes a scheduling group of tenant: its weight
+// within its parent, the policy dividing its share between its own queue
+// and its subgroups, and the strategy of its own queue. It returns the
+// group's status before and after, nil where the group does not exist.
+func (ts *TaskService) SetGroup(tenant string, req dto.GroupRequest) (previous, current *dto.GroupStatus, err error) {
+	if _, err := scheduler.ParseGroupPath(req.Path); err != nil {
+		return nil, nil, err
+	}
+	if req.Weight < 0 {
+		return nil, nil, fmt.Errorf("group weight cannot be negative: %d", req.Weight)
+	}
+	if req.Strategy != "" && !slices.Contains(ts.engine.GetAvailableStrategies(), req.Strategy) {
+		return nil, nil, fmt.Errorf("unsupported scheduler strategy: %s", req.Strategy)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
 
-		result := models.ScheduleResult{
-			Time:           ts.currentTime,
-			TaskIndexes:    indexes,
-			RemainingTimes: remainingTimes,
+	ts.tenant(tenant)
+	previous = ts.groupStatus(tenant, req.Path)
+	err = ts.setGroup(tenant, req)
+	current = ts.groupStatus(tenant, req.Path)
+	ts.publish()
+	return previous, current, err
+}
+
+// setGroup applies req to the group; ts.mu must be held.
+func (ts *TaskService) setGroup(tenant string, req dto.GroupRequest) error {
+	if err := ts.engine.SetGroup(tenant, req.Path, req.Weight, req.Policy); err != nil {
+		return err
+	}
+	for _, manager := range ts.engine.GroupManagers(tenant, req.Path) {
+		if req.Preemptive != nil {
+			strategy := req.Strategy
+			if strategy == "" {
+				strategy = manager.GetCurrentScheduler().GetName()
+			}
+			if err := manager.SetPreemptive(strategy, *req.Preemptive); err != nil {
+				return err
+			}
+		}
+		if req.Strategy != "" {
+			if err := manager.SwitchScheduler(req.Strategy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// groupStatus returns the status of tenant's group at path in the first
+// class that has it, or nil if there is none.
+func (ts *TaskService) groupStatus(tenant, path string) *dto.GroupStatus {
+	for _, group := range ts.engine.Groups(tenant) {
+		if group.Path == path {
+			status := newGroupStatus(group)
+			return &status
+		}
+	}
+	return nil
+}
+
+func (ts *TaskService) ExecuteSchedulingCycle() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.mergeIngested()
+	if tasks := ts.admission.unpark(); len(tasks) > 0 {
+		ts.admit(tasks)
+	}
+	ts.releaseDelayedTasks()
+	if ts.engine.GetTasksLen() == 0 {
+		// 只有延迟任务时时间照常推进
+		if ts.delayedLen() > 0 {
+			ts.engine.Advance()
+			ts.publish()
 		}
-		ts.scheduleHistory = append(ts.scheduleHistory, result)
+		return
 	}
 
+	scheduledTasks := ts.engine.Schedule()
+
+	// 每个租户只记录自己任务的调度结果
+	results := make(map[string]*models.ScheduleResult)
+	for _, task := range scheduledTasks {
+		result, exists := results[task.Tenant]
+		if !exists {
+			result = &models.ScheduleResult{Time: ts.engine.CurrentTime()}
+			results[task.Tenant] = result
+		}
+		result.TaskIndexes = append(result.TaskIndexes, task.Index)
+		result.RemainingTimes = append(result.RemainingTimes, task.RemainingTime)
+		result.Allocations = append(result.Allocations, task.Allocated)
+		if task.IsFailed {
+			result.FailedIndexes = append(result.FailedIndexes, task.Index)
+		}
+		if task.Overhead > 0 {
+			result.Overhead += task.Overhead
+			ts.metrics.ContextSwitches++
+		}
+		ts.metrics.SwitchOverhead += task.Overhead
+		ts.metrics.AllocatedBandwidth += task.Allocated
+		ts.admission.update(task.Tenant, 0, -task.Worked)
+	}
+	// 上个周期运行的任务这个周期可能被抢占，其租户的状态也会变化
+	for _, tenant := range ts.running {
+		ts.changed[tenant] = true
+	}
+	ts.running = ts.running[:0]
+	for tenant, result := range results {
+		state := ts.tenant(tenant)
+		state.scheduleHistory = append(state.scheduleHistory, *result)
+		ts.running = append(ts.running, tenant)
+	}
+	ts.usage.record(ts.engine.Now(), scheduledTasks)
+
 	ts.moveCompletedTasks(scheduledTasks)
-	ts.currentTime++
+	ts.handleFailedTasks(scheduledTasks)
+	ts.engine.Advance()
+	ts.publish()
+}
+
+// releaseDelayedTasks moves delayed tasks that became eligible into the
+// current scheduler of their group.
+func (ts *TaskService) releaseDelayedTasks() {
+	for name, state := range ts.tenants {
+		for _, task := range state.delayQueue.PopEligible(ts.engine.CurrentTime(), ts.engine.Now()) {
+			ts.engine.ClassGroup(task.Class, name, task.Group).GetCurrentScheduler().AddTasks(task)
+			ts.changed[name] = true
+		}
+	}
+}
+
+// delayedLen returns the number of delayed tasks of all tenants.
+func (ts *TaskService) delayedLen() int {
+	total := 0
+	for _, state := range ts.tenants {
+		total += state.delayQueue.Len()
+	}
+	return total
 }
 
 func (ts *TaskService) moveCompletedTasks(tasks []*models.Task) {
 	for _, task := range tasks {
 		if task.IsCompleted {
-			ts.completedTasks = append(ts.completedTasks, task)
+			state := ts.tenant(task.Tenant)
+			state.completedTasks = append(state.completedTasks, *task)
+			ts.metrics.CompletedTasks++
+			if task.Deadline > 0 && ts.engine.CurrentTime() >= task.Deadline {
+				ts.metrics.MissedDeadlines++
+			}
+			ts.predictMu.Lock()
+			ts.predictions.Observe(task.Labels, task.Duration)
+			ts.predictMu.Unlock()
+			ts.learned++
+			ts.recordEstimate(task)
+			ts.admission.update(task.Tenant, -1, 0)
+			ts.finishJobTask(task.JobID)
 		}
 	}
 }
 
-func (ts *TaskService) getActiveTasksCopy() []models.Task {
-	var result []models.Task
-	scheduler := ts.schedulerManager.GetCurrentScheduler()
-	var tempTasks []models.Task
-	for scheduler.GetTasksLen() > 0 {
-		task, exists := scheduler.GetNextTask()
-		if exists && !task.IsCompleted {
-			tempTasks = append(tempTasks, task)
-			result = append(result, task)
+// predictEstimate fills in the estimate of a task submitted without one
+// from the completed tasks that carried the same labels, falling back to
+// its duration.
+func (ts *TaskService) predictEstimate(task *models.Task) {
+	ts.predictMu.RLock()
+	prediction, ok := ts.predictions.Predict(task.Labels, 0)
+	ts.predictMu.RUnlock()
+	if !ok {
+		task.Estimate = task.Duration
+		return
+	}
+	task.Estimate = prediction
+	task.Predicted = true
+	// 未给出运行时间的任务按预测值运行
+	if task.Duration == 0 {
+		task.Duration = prediction
+		task.RemainingTime = prediction
+	}
+}
+
+// reestimate revises the estimate of a running predicted task from the
+// completions that took longer than it has run so far.
+func (ts *TaskService) reestimate(task *models.Task) int {
+	if prediction, ok := ts.predictions.Predict(task.Labels, task.Elapsed()); ok {
+		return prediction
+	}
+	return task.Revised
+}
+
+// failureModel fails a task following its failure script, or else with its
+// failure probability drawn from the seeded generator.
+func (ts *TaskService) failureModel(task *models.Task) bool {
+	if len(task.FailureScript) > 0 {
+		return task.Executions < len(task.FailureScript) && task.FailureScript[task.Executions]
+	}
+	return task.FailureProbability > 0 && ts.rng.Float64() < task.FailureProbability
+}
+
+// handleFailedTasks retries failed tasks after their backoff through the
+// delay queue, or moves them to the dead-letter list once retries run out.
+func (ts *TaskService) handleFailedTasks(tasks []*models.Task) {
+	for _, task := range tasks {
+		if !task.IsFailed {
+			continue
+		}
+
+		task.Failures++
+		// 超出估计被终止的任务重试也会再次超出
+		if task.Killed || !task.Retry.ShouldRetry(task.Failures) {
+			if task.Killed {
+				ts.metrics.KilledTasks++
+				ts.recordEstimate(task)
+			}
+			state := ts.tenant(task.Tenant)
+			state.deadTasks = append(state.deadTasks, task)
+			ts.admission.update(task.Tenant, -1, -task.RemainingTime)
+			ts.finishJobTask(task.JobID)
+			continue
 		}
+
+		retry := *task
+		retry.Restart(task.Retry.Restart == models.RestartCheckpoint)
+		ts.admission.update(task.Tenant, 0, retry.RemainingTime-task.RemainingTime)
+		retry.NotBefore = ts.engine.CurrentTime() + 1 + task.Retry.BackoffTicks
+		ts.tenant(task.Tenant).delayQueue.Add(retry)
+	}
+}
+
+// recordEstimate adds a finished task to the estimate accuracy metrics.
+func (ts *TaskService) recordEstimate(task *models.Task) {
+	if task.Estimate <= 0 {
+		return
+	}
+	ts.metrics.EstimatedTasks++
+	if task.Predicted {
+		ts.metrics.PredictedTasks++
 	}
-	for _, task := range tempTasks {
-		scheduler.AddTasks(task)
+	ts.metrics.Extensions += task.Extensions
+	ts.estimateErrorSum += max(task.Estimate-task.Duration, task.Duration-task.Estimate)
+	if task.Duration > task.Estimate {
+		ts.metrics.Underestimated++
 	}
+}
 
-	return result
+// GetMetrics returns the metrics of the latest published snapshot without taking the lock.
+func (ts *TaskService) GetMetrics() models.Metrics {
+	return ts.snapshot.Load().metrics
 }
 
-func (ts *TaskService) getCompletedTasksCopy() []models.Task {
-	var result []models.Task
-	for _, task := range ts.completedTasks {
+// GetDeadTasks returns the tasks of tenant that failed after exhausting their retries.
+func (ts *TaskService) GetDeadTasks(tenant string) []models.Task {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	state, exists := ts.tenants[tenant]
+	if !exists {
+		return make([]models.Task, 0)
+	}
+	result := make([]models.Task, 0, len(state.deadTasks))
+	for _, task := range state.deadTasks {
 		result = append(result, *task)
 	}
 	return result
 }
 
+func (ts *TaskService) addJobTasks(jobID string, count int) {
+	ts.jobsMu.Lock()
+	defer ts.jobsMu.Unlock()
+	ts.activeJobs[jobID] += count
+}
+
+func (ts *TaskService) finishJobTask(jobID string) {
+	ts.jobsMu.Lock()
+	defer ts.jobsMu.Unlock()
+	ts.activeJobs[jobID]--
+	if ts.activeJobs[jobID] <= 0 {
+		delete(ts.activeJobs, jobID)
+	}
+}
+
+// IsJobActive reports whether any task of the job has not finished yet.
+func (ts *TaskService) IsJobActive(jobID string) bool {
+	ts.jobsMu.Lock()
+	defer ts.jobsMu.Unlock()
+	return ts.activeJobs[jobID] > 0
+}
+
 func (ts *TaskService) HasActiveTasks() bool {
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
-	return ts.schedulerManager.GetCurrentScheduler().GetTasksLen() > 0
+	return ts.engine.GetTasksLen() > 0 || ts.delayedLen() > 0 || ts.ingest.len() > 0 || ts.admission.parkedLen("") > 0
 }
 
 func (ts *TaskService) GetAvailableStrategies() []string {
-	return ts.schedulerManager.GetAvailableStrategies()
+	return ts.engine.GetAvailableStrategies()
+}
+
+func cloneLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(labels))
+	for k, v := range labels {
+		clone[k] = v
+	}
+	return clone
 }
@@ -1,14 +1,20 @@
 package services
 
 import (
+	"errors"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"scheduler-service/scheduler"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestNewTaskService(t *testing.T) {
 	bandwidth := 5
 	service := NewTaskService(bandwidth)
 
-	status := service.GetStatus()
+	status := service.GetStatus(models.DefaultTenant)
 	if status.CurrentTime != 0 {
 		t.Errorf("Expected CurrentTime to be 0, got %d", status.CurrentTime)
 	}
@@ -30,7 +36,7 @@ func TestTaskService_SubmitTasks(t *testing.T) {
 	service := NewTaskService(5)
 
 	timeSlices := []int{3, 5, 2}
-	response, err := service.SubmitTasks(timeSlices)
+	response, err := service.SubmitTasks(models.DefaultTenant, timeSlices)
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -45,7 +51,7 @@ func TestTaskService_SubmitTasks(t *testing.T) {
 		t.Error("Expected JobID to be non-empty")
 	}
 
-	status := service.GetStatus()
+	status := service.GetStatus(models.DefaultTenant)
 	if len(status.ActiveTasks) != len(timeSlices) {
 		t.Errorf("Expected %d active tasks, got %d",
 			len(timeSlices), len(status.ActiveTasks))
@@ -57,7 +63,7 @@ func TestTaskService_ExecuteSchedulingCycle(t *testing.T) {
 
 	// 提交任务
 	timeSlices := []int{3, 5, 2}
-	_, err := service.SubmitTasks(timeSlices)
+	_, err := service.SubmitTasks(models.DefaultTenant, timeSlices)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -65,7 +71,7 @@ func TestTaskService_ExecuteSchedulingCycle(t *testing.T) {
 	// 执行一个调度周期
 	service.ExecuteSchedulingCycle()
 
-	status := service.GetStatus()
+	status := service.GetStatus(models.DefaultTenant)
 	if status.CurrentTime != 1 {
 		t.Errorf("Expected CurrentTime to be 1, got %d", status.CurrentTime)
 	}
@@ -101,25 +107,762 @@ func TestTaskService_SwitchScheduler(t *testing.T) {
 	service := NewTaskService(5)
 
 	// 切换到SRTF
-	err := service.SwitchScheduler("SRTF")
+	err := service.SwitchScheduler(models.DefaultTenant, "SRTF")
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	status := service.GetStatus()
+	status := service.GetStatus(models.DefaultTenant)
 	if status.CurrentStrategy != "SRTF" {
 		t.Errorf("Expected strategy to be SRTF, got %s", status.CurrentStrategy)
 	}
 
 	// 切换到无效的调度器
-	err = service.SwitchScheduler("INVALID")
+	err = service.SwitchScheduler(models.DefaultTenant, "INVALID")
 	if err == nil {
 		t.Error("Expected error for invalid scheduler, got nil")
 	}
 
 	// 确保调度器没有改变
-	status = service.GetStatus()
+	status = service.GetStatus(models.DefaultTenant)
 	if status.CurrentStrategy != "SRTF" {
 		t.Errorf("Expected strategy to remain SRTF, got %s", status.CurrentStrategy)
 	}
 }
+
+func TestTaskService_UpdateScheduler(t *testing.T) {
+	service := NewTaskService(5)
+	off := false
+
+	tests := []struct {
+		name             string
+		req              dto.SchedulerSwitchRequest
+		expectedPrevious dto.SchedulerState
+		expectedCurrent  dto.SchedulerState
+		expectError      bool
+	}{
+		{"Switch", dto.SchedulerSwitchRequest{Strategy: "SRTF"},
+			dto.SchedulerState{Strategy: "FIFO", Preemptive: true}, dto.SchedulerState{Strategy: "SRTF", Preemptive: true}, false},
+		{"Switch and turn off preemption", dto.SchedulerSwitchRequest{Strategy: "FIFO", Preemptive: &off},
+			dto.SchedulerState{Strategy: "SRTF", Preemptive: true}, dto.SchedulerState{Strategy: "FIFO", Preemptive: false}, false},
+		{"Invalid", dto.SchedulerSwitchRequest{Strategy: "INVALID", Preemptive: &off},
+			dto.SchedulerState{Strategy: "FIFO", Preemptive: false}, dto.SchedulerState{Strategy: "FIFO", Preemptive: false}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			previous, current, err := service.UpdateScheduler(models.DefaultTenant, tc.req)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected error %v, got %v", tc.expectError, err)
+			}
+			if previous != tc.expectedPrevious || current != tc.expectedCurrent {
+				t.Errorf("Expected %+v -> %+v, got %+v -> %+v", tc.expectedPrevious, tc.expectedCurrent, previous, current)
+			}
+		})
+	}
+}
+
+func TestTaskService_SubmitRigidTasks(t *testing.T) {
+	service := NewTaskService(5)
+
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 4, Width: 6}}); !errors.Is(err, ErrInvalidTask) {
+		t.Fatalf("Expected ErrInvalidTask for width over bandwidth, got %v", err)
+	}
+
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 2}, {Duration: 4, Width: 4}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	service.ExecuteSchedulingCycle()
+	service.ExecuteSchedulingCycle()
+
+	status := service.GetStatus(models.DefaultTenant)
+	if len(status.CompletedTasks) != 2 {
+		t.Fatalf("Expected both tasks to complete, got %d", len(status.CompletedTasks))
+	}
+	if allocations := status.ScheduleHistory[1].Allocations; len(allocations) != 1 || allocations[0] != 4 {
+		t.Errorf("Expected the rigid task to get exactly 4 units, got %v", allocations)
+	}
+}
+
+func TestTaskService_SubmitMalleableTasks(t *testing.T) {
+	service := NewTaskService(5)
+
+	invalid := []dto.TaskSpec{
+		{Duration: 4, Width: 2, MaxParallelism: 2},
+		{Duration: 4, MinParallelism: 6},
+		{Duration: 4, MinParallelism: 3, MaxParallelism: 2},
+		{Duration: 4, Speedup: &models.SpeedupModel{Model: "unknown"}},
+	}
+	for _, spec := range invalid {
+		if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{spec}); !errors.Is(err, ErrInvalidTask) {
+			t.Errorf("Expected ErrInvalidTask for %+v, got %v", spec, err)
+		}
+	}
+
+	spec := dto.TaskSpec{
+		Duration:       4,
+		MaxParallelism: 2,
+		Speedup:        &models.SpeedupModel{Model: models.SpeedupLinear},
+	}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{spec}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	service.ExecuteSchedulingCycle()
+	status := service.GetStatus(models.DefaultTenant)
+	if allocations := status.ScheduleHistory[0].Allocations; len(allocations) != 1 || allocations[0] != 2 {
+		t.Errorf("Expected the task to be capped at 2 units, got %v", allocations)
+	}
+}
+
+func TestTaskService_DelayedTasks(t *testing.T) {
+	service := NewTaskService(5)
+	now := time.Now()
+	service.engine.SetClock(func() time.Time { return now })
+
+	specs := []dto.TaskSpec{
+		{Duration: 1, NotBefore: 2},
+		{Duration: 1, StartAt: now.Add(time.Minute)},
+	}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, specs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	status := service.GetStatus(models.DefaultTenant)
+	if len(status.ScheduledTasks) != 2 || len(status.ActiveTasks) != 0 {
+		t.Fatalf("Expected 2 scheduled and 0 active tasks, got %d and %d",
+			len(status.ScheduledTasks), len(status.ActiveTasks))
+	}
+	if !service.HasActiveTasks() {
+		t.Error("Expected delayed tasks to keep the scheduler running")
+	}
+
+	// 第0、1周期任务仍在等待，第2周期开始运行
+	service.ExecuteSchedulingCycle()
+	service.ExecuteSchedulingCycle()
+	service.ExecuteSchedulingCycle()
+
+	status = service.GetStatus(models.DefaultTenant)
+	if len(status.CompletedTasks) != 1 || len(status.ScheduledTasks) != 1 {
+		t.Fatalf("Expected 1 completed and 1 scheduled task, got %d and %d",
+			len(status.CompletedTasks), len(status.ScheduledTasks))
+	}
+	if history := status.ScheduleHistory; len(history) != 1 || history[0].Time != 2 {
+		t.Errorf("Expected the delayed task to run at tick 2, got %v", history)
+	}
+
+	now = now.Add(time.Minute)
+	service.ExecuteSchedulingCycle()
+	status = service.GetStatus(models.DefaultTenant)
+	if len(status.CompletedTasks) != 2 || len(status.ScheduledTasks) != 0 {
+		t.Errorf("Expected the timed task to run once its start time passed, got %d completed",
+			len(status.CompletedTasks))
+	}
+}
+
+func TestTaskService_SetBandwidth(t *testing.T) {
+	service := NewTaskService(5)
+	specs := []dto.TaskSpec{{Duration: 1, Width: 4, NotBefore: 2}}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, specs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 延迟任务尚未进入调度器，也不能因带宽缩小而永远无法运行
+	if _, err := service.SetBandwidth(3); err == nil {
+		t.Error("Expected a delayed task wider than the bandwidth to refuse the change")
+	}
+	if previous, err := service.SetBandwidth(4); err != nil || previous != 5 {
+		t.Fatalf("Expected the previous bandwidth 5, got %d, %v", previous, err)
+	}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 1, Width: 5}}); !errors.Is(err, ErrInvalidTask) {
+		t.Errorf("Expected a task wider than the new bandwidth to be rejected, got %v", err)
+	}
+}
+
+func TestTaskService_RetryAndDeadLetter(t *testing.T) {
+	service := NewTaskService(5)
+
+	specs := []dto.TaskSpec{
+		// 第一次执行失败，退避1个周期后从头重试
+		{Duration: 3, FailureScript: []bool{true}, Retry: &models.RetryPolicy{MaxAttempts: 2, BackoffTicks: 1}},
+		// 没有重试策略，直接进入死信队列
+		{Duration: 3, FailureProbability: 1},
+	}
+	response, err := service.SubmitTaskSpecs(models.DefaultTenant, specs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	service.ExecuteSchedulingCycle()
+	status := service.GetStatus(models.DefaultTenant)
+	if failed := status.ScheduleHistory[0].FailedIndexes; len(failed) != 2 {
+		t.Fatalf("Expected both tasks to fail in the first cycle, got %v", failed)
+	}
+	if len(service.GetDeadTasks(models.DefaultTenant)) != 1 || len(status.ScheduledTasks) != 1 {
+		t.Fatalf("Expected 1 dead and 1 retrying task, got %d and %d",
+			len(service.GetDeadTasks(models.DefaultTenant)), len(status.ScheduledTasks))
+	}
+	if !service.IsJobActive(response.JobID) {
+		t.Error("Expected job to stay active while a task is retrying")
+	}
+
+	service.ExecuteSchedulingCycle() // 退避中
+	service.ExecuteSchedulingCycle()
+
+	status = service.GetStatus(models.DefaultTenant)
+	if len(status.CompletedTasks) != 1 || status.CompletedTasks[0].Failures != 1 {
+		t.Fatalf("Expected the retried task to complete after one failure, got %v", status.CompletedTasks)
+	}
+	if history := status.ScheduleHistory; history[len(history)-1].Time != 2 {
+		t.Errorf("Expected the retry to run at tick 2, got %d", history[len(history)-1].Time)
+	}
+	if service.IsJobActive(response.JobID) {
+		t.Error("Expected job to finish once every task completed or died")
+	}
+
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 1, FailureProbability: 2}}); !errors.Is(err, ErrInvalidTask) {
+		t.Errorf("Expected ErrInvalidTask for probability over 1, got %v", err)
+	}
+}
+
+func TestTaskService_Metrics(t *testing.T) {
+	service := NewTaskService(5)
+	if err := service.SetContextSwitch(1, "change"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := service.SubmitTasks(models.DefaultTenant, []int{3, 6}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service.ExecuteSchedulingCycle()
+
+	status := service.GetStatus(models.DefaultTenant)
+	if status.ScheduleHistory[0].Overhead != 2 {
+		t.Errorf("Expected both starting tasks to pay 1 unit, got %d", status.ScheduleHistory[0].Overhead)
+	}
+
+	metrics := service.GetMetrics()
+	if metrics.ContextSwitches != 2 || metrics.SwitchOverhead != 2 {
+		t.Errorf("Expected 2 switches costing 2 units, got %d and %d",
+			metrics.ContextSwitches, metrics.SwitchOverhead)
+	}
+	if metrics.AllocatedBandwidth != 5 || metrics.CurrentTime != 1 {
+		t.Errorf("Expected 5 units allocated at time 1, got %d at %d",
+			metrics.AllocatedBandwidth, metrics.CurrentTime)
+	}
+}
+
+func TestTaskService_Estimates(t *testing.T) {
+	service := NewTaskService(5)
+	specs := []dto.TaskSpec{
+		{Duration: 6, Estimate: 2, OnUnderestimate: models.UnderestimateKill},
+		{Duration: 3, Estimate: 5},
+	}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, specs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for service.HasActiveTasks() {
+		service.ExecuteSchedulingCycle()
+	}
+
+	dead := service.GetDeadTasks(models.DefaultTenant)
+	if len(dead) != 1 || !dead[0].Killed {
+		t.Fatalf("Expected the underestimated task to be killed, got %v", dead)
+	}
+
+	metrics := service.GetMetrics()
+	if metrics.EstimatedTasks != 2 || metrics.KilledTasks != 1 || metrics.Underestimated != 1 {
+		t.Errorf("Expected 2 estimated tasks with 1 killed underestimate, got %+v", metrics)
+	}
+	if metrics.EstimateError != 3 {
+		t.Errorf("Expected mean absolute estimate error 3, got %v", metrics.EstimateError)
+	}
+
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 3, OnUnderestimate: "retry"}}); !errors.Is(err, ErrInvalidTask) {
+		t.Errorf("Expected ErrInvalidTask, got %v", err)
+	}
+}
+
+func TestTaskService_PredictedEstimates(t *testing.T) {
+	service := NewTaskService(5)
+	labels := map[string]string{"app": "etl"}
+
+	history := []dto.TaskSpec{{Duration: 4, Labels: labels}, {Duration: 8, Labels: labels}}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, history); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for service.HasActiveTasks() {
+		service.ExecuteSchedulingCycle()
+	}
+
+	specs := []dto.TaskSpec{{Duration: 10, Labels: labels}, {Labels: labels}}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, specs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	active := service.GetStatus(models.DefaultTenant).ActiveTasks
+	if len(active) != 2 {
+		t.Fatalf("Expected 2 active tasks, got %d", len(active))
+	}
+	for _, task := range active {
+		if !task.Predicted || task.Estimate != 6 {
+			t.Errorf("Expected predicted estimate 6, got %+v", task)
+		}
+	}
+	if active[1].Duration != 6 {
+		t.Errorf("Expected a task without duration to run for the prediction, got %d", active[1].Duration)
+	}
+
+	// 运行超过最短的历史记录后，估计按更长的完成记录修正
+	service.ExecuteSchedulingCycle()
+	active = service.GetStatus(models.DefaultTenant).ActiveTasks
+	if active[0].Revised != 8 {
+		t.Errorf("Expected the estimate to be revised to 8, got %+v", active[0])
+	}
+
+	for service.HasActiveTasks() {
+		service.ExecuteSchedulingCycle()
+	}
+	if metrics := service.GetMetrics(); metrics.PredictedTasks != 2 {
+		t.Errorf("Expected 2 predicted tasks, got %d", metrics.PredictedTasks)
+	}
+}
+
+func TestTaskService_UpdateAndMoveTask(t *testing.T) {
+	service := NewTaskService(5)
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 5}, {Duration: 5}, {Duration: 5}, {Duration: 5, NotBefore: 10}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status := service.GetStatus(models.DefaultTenant)
+	first, second, third := status.ActiveTasks[0].Index, status.ActiveTasks[1].Index, status.ActiveTasks[2].Index
+	delayed := status.ScheduledTasks[0].Index
+
+	priority, deadline := 2, 30
+	previous, task, err := service.UpdateTask(models.DefaultTenant, delayed, dto.TaskUpdateRequest{Priority: &priority, Deadline: &deadline, Labels: map[string]string{"team": "ops"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.Priority != 2 || task.Deadline != 30 || task.Labels["team"] != "ops" {
+		t.Errorf("Expected the delayed task to be updated, got %+v", task)
+	}
+	if previous.Priority != 0 || previous.Deadline != 0 || previous.Labels["team"] != "" {
+		t.Errorf("Expected the task before the update, got %+v", previous)
+	}
+
+	if _, _, err := service.MoveTask(models.DefaultTenant, third, dto.TaskMoveRequest{Before: &second}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if active := service.GetStatus(models.DefaultTenant).ActiveTasks; active[1].Index != third || active[2].Index != second {
+		t.Errorf("Expected task %d moved immediately ahead of %d, got %+v", third, second, active)
+	}
+
+	if _, _, err := service.MoveTask(models.DefaultTenant, second, dto.TaskMoveRequest{Front: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service.ExecuteSchedulingCycle()
+	if ran := service.GetStatus(models.DefaultTenant).ScheduleHistory[0].TaskIndexes[0]; ran != second {
+		t.Errorf("Expected task %d moved to the front to run next, got %d", second, ran)
+	}
+
+	// 已经优先级最高的任务移到最前面时保持原优先级
+	priority = 9
+	if _, _, err := service.UpdateTask(models.DefaultTenant, first, dto.TaskUpdateRequest{Priority: &priority}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, task, err := service.MoveTask(models.DefaultTenant, first, dto.TaskMoveRequest{Front: true}); err != nil || task.Priority != 9 {
+		t.Errorf("Expected task %d to keep priority 9, got %+v, %v", first, task, err)
+	}
+
+	if _, _, err := service.UpdateTask(models.DefaultTenant, first+100, dto.TaskUpdateRequest{}); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	if _, _, err := service.MoveTask(models.DefaultTenant, first, dto.TaskMoveRequest{}); !errors.Is(err, ErrInvalidTask) {
+		t.Errorf("Expected ErrInvalidTask, got %v", err)
+	}
+}
+
+func TestTaskService_MoveTaskBefore(t *testing.T) {
+	service := NewTaskService(1)
+	if _, err := service.SubmitTasks(models.DefaultTenant, []int{5, 5, 5, 5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	order := func() []int {
+		var indexes []int
+		for _, task := range service.GetStatus(models.DefaultTenant).ActiveTasks {
+			indexes = append(indexes, task.Index)
+		}
+		return indexes
+	}
+	tasks := order()
+	a, b, c, d := tasks[0], tasks[1], tasks[2], tasks[3]
+
+	// 移到c之前不能越过同名次、排在c之前的a和b
+	moves := []struct {
+		index, before int
+		expected      []int
+	}{
+		{d, c, []int{a, b, d, c}},
+		{a, d, []int{b, a, d, c}},
+		{c, b, []int{c, b, a, d}},
+	}
+	for _, move := range moves {
+		if _, _, err := service.MoveTask(models.DefaultTenant, move.index, dto.TaskMoveRequest{Before: &move.before}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := order(); !slices.Equal(got, move.expected) {
+			t.Errorf("Moving %d before %d: expected order %v, got %v", move.index, move.before, move.expected, got)
+		}
+	}
+}
+
+func TestTaskService_CancelTask(t *testing.T) {
+	service := NewTaskService(5)
+	response, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 5}, {Duration: 5, NotBefore: 10}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status := service.GetStatus(models.DefaultTenant)
+	queued, delayed := status.ActiveTasks[0].Index, status.ScheduledTasks[0].Index
+
+	for _, index := range []int{queued, delayed} {
+		if _, err := service.CancelTask(models.DefaultTenant, index); err != nil {
+			t.Fatalf("Unexpected error cancelling task %d: %v", index, err)
+		}
+	}
+	if _, err := service.CancelTask(models.DefaultTenant, queued); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+
+	if service.HasActiveTasks() || service.IsJobActive(response.JobID) {
+		t.Error("Expected no active tasks after cancelling the whole job")
+	}
+	if metrics := service.GetMetrics(); metrics.CancelledTasks != 2 {
+		t.Errorf("Expected 2 cancelled tasks, got %d", metrics.CancelledTasks)
+	}
+}
+
+func TestTaskService_StatusSnapshot(t *testing.T) {
+	service := NewTaskService(5)
+	initial := service.GetStatus(models.DefaultTenant).Version
+
+	if _, err := service.SubmitTasks(models.DefaultTenant, []int{3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	submitted := service.GetStatus(models.DefaultTenant)
+	if submitted.Version <= initial || len(submitted.ActiveTasks) != 1 {
+		t.Fatalf("Expected a newer snapshot with 1 active task, got version %d and %d tasks",
+			submitted.Version, len(submitted.ActiveTasks))
+	}
+
+	service.ExecuteSchedulingCycle()
+	executed := service.GetStatus(models.DefaultTenant)
+	if executed.Version <= submitted.Version || len(executed.CompletedTasks) != 1 {
+		t.Errorf("Expected a newer snapshot with 1 completed task, got version %d and %d tasks",
+			executed.Version, len(executed.CompletedTasks))
+	}
+	if len(submitted.CompletedTasks) != 0 {
+		t.Error("Expected an earlier snapshot to stay unchanged")
+	}
+
+	// 写锁被占用时读取也不阻塞
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		service.GetStatus(models.DefaultTenant)
+		service.GetMetrics()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected status reads not to wait for the lock")
+	}
+}
+
+func TestTaskService_StatusSnapshotChangedTenants(t *testing.T) {
+	service := NewTaskService(2)
+	for tenant, durations := range map[string][]int{"a": {5}, "b": {1}} {
+		if _, err := service.SubmitTasks(tenant, durations); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	service.ExecuteSchedulingCycle()
+	service.ExecuteSchedulingCycle()
+	published := func(tenant string) *dto.StatusResponse {
+		status, _ := service.statuses.Load(tenant)
+		return status.(*dto.StatusResponse)
+	}
+
+	// b的任务已完成，之后的周期和其他租户的提交都不重建它的状态
+	idle := published("b")
+	if _, err := service.SubmitTasks("c", []int{3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service.ExecuteSchedulingCycle()
+	if published("b") != idle {
+		t.Error("Expected the status of an unchanged tenant not to be rebuilt")
+	}
+	status := service.GetStatus("b")
+	if status.CurrentTime != 3 || status.Version != service.GetStatus("a").Version || len(status.CompletedTasks) != 1 {
+		t.Errorf("Expected b at time 3 with the latest version and 1 completed task, got %+v", status)
+	}
+
+	// 修改和取消排队的任务会重建所在租户的状态
+	if _, err := service.SubmitTasks("b", []int{4}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	queued := service.GetStatus("b").ActiveTasks[0].Index
+	priority := 7
+	if _, _, err := service.UpdateTask("b", queued, dto.TaskUpdateRequest{Priority: &priority}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := service.GetStatus("b").ActiveTasks[0].Priority; got != priority {
+		t.Errorf("Expected the updated priority %d in the status, got %d", priority, got)
+	}
+	if _, err := service.CancelTask("b", queued); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status := service.GetStatus("b"); status.ActiveCount != 0 {
+		t.Errorf("Expected no active tasks after cancelling, got %d", status.ActiveCount)
+	}
+}
+
+func TestNewTaskService_InvalidBandwidth(t *testing.T) {
+	// 带宽来自配置时用NewEngine得到错误，而不是NewTaskService的panic
+	if _, err := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: 0}); err == nil {
+		t.Error("Expected NewEngine to reject bandwidth 0")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewTaskService to panic with bandwidth 0")
+		}
+	}()
+	NewTaskService(0)
+}
+
+func TestTaskService_IndependentEngines(t *testing.T) {
+	engine, err := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: 3, Strategies: []string{"SRTF"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taskServices := []*TaskService{NewTaskService(5), NewTaskServiceWithEngine(engine)}
+
+	for i, service := range taskServices {
+		if _, err := service.SubmitTasks(models.DefaultTenant, []int{4, 2}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		indexes := []int{service.GetStatus(models.DefaultTenant).ActiveTasks[0].Index, service.GetStatus(models.DefaultTenant).ActiveTasks[1].Index}
+		if indexes[0]+indexes[1] != 1 {
+			t.Errorf("Service %d: expected its own indexes 0 and 1, got %v", i, indexes)
+		}
+	}
+
+	taskServices[1].ExecuteSchedulingCycle()
+	if taskServices[0].GetStatus(models.DefaultTenant).CurrentTime != 0 || taskServices[1].GetStatus(models.DefaultTenant).CurrentTime != 1 {
+		t.Error("Expected each engine to keep its own clock")
+	}
+	if err := taskServices[1].SwitchScheduler(models.DefaultTenant, "FIFO"); err == nil {
+		t.Error("Expected a strategy outside the engine's set to be rejected")
+	}
+}
+
+func TestTaskService_Tenants(t *testing.T) {
+	service := NewTaskService(4)
+
+	if _, err := service.SubmitTasks("team-a", []int{5, 5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.SubmitTasks("team-b", []int{1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.SwitchScheduler("team-a", "SRTF"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	statusA, statusB := service.GetStatus("team-a"), service.GetStatus("team-b")
+	if len(statusA.ActiveTasks) != 2 || len(statusB.ActiveTasks) != 1 {
+		t.Fatalf("Expected each tenant to see only its own tasks, got %d and %d",
+			len(statusA.ActiveTasks), len(statusB.ActiveTasks))
+	}
+	if statusA.CurrentStrategy != "SRTF" || statusB.CurrentStrategy != "FIFO" {
+		t.Errorf("Expected a strategy switch to affect one tenant, got %s and %s",
+			statusA.CurrentStrategy, statusB.CurrentStrategy)
+	}
+	if _, err := service.CancelTask("team-b", statusA.ActiveTasks[0].Index); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected another tenant's task to be invisible, got %v", err)
+	}
+
+	service.ExecuteSchedulingCycle()
+	statusA, statusB = service.GetStatus("team-a"), service.GetStatus("team-b")
+	// 两个租户平分4个单位，team-b只用得上1个
+	if len(statusA.ScheduleHistory) != 1 || statusA.ScheduleHistory[0].Allocations[0] != 2 {
+		t.Errorf("Expected team-a's history to show its fair share of 2 units, got %+v", statusA.ScheduleHistory)
+	}
+	if len(statusB.ScheduleHistory) != 1 || statusB.ScheduleHistory[0].TaskIndexes[0] == statusA.ActiveTasks[0].Index {
+		t.Errorf("Expected team-b's history to list only its own task, got %+v", statusB.ScheduleHistory)
+	}
+	if len(statusB.CompletedTasks) != 1 || len(statusA.CompletedTasks) != 0 {
+		t.Errorf("Expected only team-b to complete a task, got %d and %d",
+			len(statusA.CompletedTasks), len(statusB.CompletedTasks))
+	}
+
+	unknown := service.GetStatus("team-c")
+	if len(unknown.ActiveTasks) != 0 || unknown.CurrentStrategy != "FIFO" || unknown.Tenant != "team-c" {
+		t.Errorf("Expected an empty status for a new tenant, got %+v", unknown)
+	}
+	if service.GetMetrics().CompletedTasks != 1 {
+		t.Errorf("Expected metrics to count all tenants, got %d", service.GetMetrics().CompletedTasks)
+	}
+}
+
+func TestTaskService_Groups(t *testing.T) {
+	service := NewTaskService(4)
+
+	previous, current, err := service.SetGroup("org", dto.GroupRequest{Path: "ops", Weight: 3, Strategy: "SRTF"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if previous != nil || current == nil || current.Weight != 3 || current.Strategy != "SRTF" {
+		t.Errorf("Expected a new ops group of weight 3 with SRTF, got %+v -> %+v", previous, current)
+	}
+	specs := []dto.TaskSpec{{Duration: 6, Group: "eng"}, {Duration: 6, Group: "ops"}, {Duration: 2, Group: "ops"}}
+	if _, err := service.SubmitTaskSpecs("org", specs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.SubmitTaskSpecs("org", []dto.TaskSpec{{Duration: 1, Group: "eng/"}}); !errors.Is(err, ErrInvalidTask) {
+		t.Errorf("Expected an invalid group path to be rejected, got %v", err)
+	}
+
+	status := service.GetStatus("org")
+	if status.ActiveCount != 3 || len(status.Groups) != 3 {
+		t.Fatalf("Expected 3 queued tasks in 3 groups, got %d in %+v", status.ActiveCount, status.Groups)
+	}
+	if ops := status.Groups[2]; ops.Path != "ops" || ops.Weight != 3 || ops.Strategy != "SRTF" || ops.QueuedTasks != 2 {
+		t.Errorf("Unexpected ops group: %+v", ops)
+	}
+
+	// ops 权重为3，分到3个单位，且按 SRTF 先运行短任务
+	service.ExecuteSchedulingCycle()
+	history := service.GetStatus("org").ScheduleHistory
+	allocated := make(map[string]int)
+	for i, index := range history[0].TaskIndexes {
+		for _, task := range status.ActiveTasks {
+			if task.Index == index {
+				allocated[task.Group] += history[0].Allocations[i]
+			}
+		}
+	}
+	if allocated["eng"] != 1 || allocated["ops"] != 3 {
+		t.Errorf("Expected eng and ops to get 1 and 3 units, got %v", allocated)
+	}
+
+	if _, err := service.CancelTask("org", status.ActiveTasks[0].Index); err != nil {
+		t.Errorf("Expected to cancel a task of a group: %v", err)
+	}
+}
+
+func TestTaskService_Partitions(t *testing.T) {
+	partitions, _ := scheduler.ParsePartitions("interactive=SRTF:3:tier=interactive,batch=FIFO:2")
+	engine, err := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: 5, Partitions: partitions})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service := NewTaskServiceWithEngine(engine)
+
+	specs := []dto.TaskSpec{
+		{Duration: 4, Labels: map[string]string{"tier": "interactive"}},
+		{Duration: 4, Labels: map[string]string{"tier": "interactive"}, Group: "eng"},
+		{Duration: 4},
+	}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, specs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	queued := make(map[string]int)
+	for _, group := range service.GetStatus(models.DefaultTenant).Groups {
+		queued[group.Path] = group.QueuedTasks
+	}
+	if queued["interactive"] != 1 || queued["interactive/eng"] != 1 || queued["batch"] != 1 {
+		t.Errorf("Expected the tasks to be routed by label, got %v", queued)
+	}
+
+	// 分区的租户只能按分区切换策略
+	if err := service.SwitchScheduler(models.DefaultTenant, "SRTF"); !errors.Is(err, ErrPartitioned) {
+		t.Errorf("Expected switching a partitioned tenant to be refused, got %v", err)
+	}
+	if _, _, err := service.SetGroup(models.DefaultTenant, dto.GroupRequest{Path: "batch", Strategy: "SRTF"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	strategies := make(map[string]string)
+	for _, group := range service.GetStatus(models.DefaultTenant).Groups {
+		strategies[group.Path] = group.Strategy
+	}
+	if strategies["interactive"] != "SRTF" || strategies["batch"] != "SRTF" {
+		t.Errorf("Expected the batch partition to switch to SRTF, got %v", strategies)
+	}
+}
+
+func TestTaskService_ServiceClasses(t *testing.T) {
+	classes, _ := scheduler.ParseServiceClasses("critical=1,standard=0,best-effort=0::preemptible")
+	engine, err := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: 2, Classes: classes, DefaultClass: "standard"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service := NewTaskServiceWithEngine(engine)
+	if err := service.SwitchScheduler(models.DefaultTenant, "EASY"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 10, Width: 2, Class: "best-effort"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 1, Class: "gold"}}); !errors.Is(err, ErrInvalidTask) {
+		t.Errorf("Expected an unknown class to be rejected, got %v", err)
+	}
+	service.ExecuteSchedulingCycle()
+
+	// 关键任务到达后，尽力而为的任务即使在非抢占策略下也让出带宽
+	if _, err := service.SubmitTaskSpecs(models.DefaultTenant, []dto.TaskSpec{{Duration: 2, Width: 2, Class: "critical"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service.ExecuteSchedulingCycle()
+	history := service.GetStatus(models.DefaultTenant).ScheduleHistory
+	if last := history[len(history)-1]; len(last.TaskIndexes) != 1 || last.Allocations[0] != 2 {
+		t.Errorf("Expected only the critical task to run, got %+v", last)
+	}
+
+	metrics := service.GetMetrics()
+	if len(metrics.Classes) != 3 || metrics.Classes[0].AllocatedBandwidth != 2 || metrics.Classes[2].AllocatedBandwidth != 2 {
+		t.Errorf("Expected critical and best-effort to get 2 units each, got %+v", metrics.Classes)
+	}
+	if metrics.Classes[0].HonoredRate != 1 || metrics.Classes[0].Utilization != 0.5 {
+		t.Errorf("Unexpected critical metrics: %+v", metrics.Classes[0])
+	}
+}
+
+func TestTaskService_BurstCredits(t *testing.T) {
+	service := NewTaskService(4)
+	if err := service.SetTenantBurst("team-a", scheduler.TokenBucket{Rate: 0.5, Capacity: 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.SubmitTaskSpecs("team-a", []dto.TaskSpec{{Duration: 8, MaxParallelism: 4}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.SubmitTaskSpecs("team-b", []dto.TaskSpec{{Duration: 8, MaxParallelism: 4}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	service.ExecuteSchedulingCycle()
+	status := service.GetStatus("team-a")
+	if status.BurstCredits == nil || *status.BurstCredits != 0 {
+		t.Fatalf("Expected team-a to spend its credits, got %v", status.BurstCredits)
+	}
+	if service.GetStatus("team-b").BurstCredits != nil {
+		t.Error("Expected team-b to have no burst credits")
+	}
+	metrics := service.GetMetrics()
+	if metrics.BurstBandwidth != 2 || metrics.BurstCredits["team-a"] != 0 {
+		t.Errorf("Expected team-a to burst 2 units, got %d and %v", metrics.BurstBandwidth, metrics.BurstCredits)
+	}
+}
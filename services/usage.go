@@ -0,0 +1,270 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidUsageQuery is returned for a usage report that cannot be built.
+var ErrInvalidUsageQuery = errors.New("invalid usage query")
+
+// usageInterval is the resolution of the usage records; report ranges are
+// rounded to it.
+const usageInterval = time.Minute
+
+// usageKey attributes the usage of one interval.
+type usageKey struct {
+	start  int64 // 区间开始的Unix秒
+	tenant string
+	job    string
+	class  string
+	labels string // 标签的规范形式，见 labelsKey
+}
+
+// usageLedger records the bandwidth-ticks consumed by every tenant, job,
+// class and label set. It has its own lock so that reports never wait for
+// a scheduling cycle. With a file, the usage of every interval is appended
+// to it as JSON lines once the interval is over.
+type usageLedger struct {
+	mu     sync.Mutex
+	prices models.UnitPrices
+	ticks  map[usageKey]int
+	labels map[string]map[string]string
+
+	file    *os.File         // nil keeps the usage in memory only
+	pending map[usageKey]int // 当前区间尚未写入文件的用量
+	current int64            // 当前区间开始的Unix秒
+}
+
+// usageEntry is one line of a usage file: the bandwidth-ticks of one
+// tenant, job, class and label set in one interval.
+type usageEntry struct {
+	Start  time.Time         `json:"start"`
+	Tenant string            `json:"tenant"`
+	Job    string            `json:"job,omitempty"`
+	Class  string            `json:"class,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Ticks  int               `json:"ticks"`
+}
+
+func newUsageLedger() *usageLedger {
+	return &usageLedger{
+		ticks:   make(map[usageKey]int),
+		labels:  make(map[string]map[string]string),
+		pending: make(map[usageKey]int),
+	}
+}
+
+// open loads the usage recorded in the file at path and appends to it from
+// then on.
+func (l *usageLedger) open(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = readJSONLines(file, func(line []byte) error {
+		var entry usageEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		l.add(entry.Start.Unix(), entry.Tenant, entry.Job, entry.Class, entry.Labels, entry.Ticks)
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("invalid usage file %s: %w", path, err)
+	}
+	l.file = file
+	return nil
+}
+
+// close writes the usage of the current interval and closes the file.
+func (l *usageLedger) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	l.flush()
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// flush appends the pending usage to the file. The cycles have already
+// run, so usage the file cannot take is logged and kept in memory.
+func (l *usageLedger) flush() {
+	if len(l.pending) == 0 {
+		return
+	}
+	var lines []byte
+	for key, ticks := range l.pending {
+		entry := usageEntry{
+			Start:  time.Unix(key.start, 0).UTC(),
+			Tenant: key.tenant,
+			Job:    key.job,
+			Class:  key.class,
+			Labels: l.labels[key.labels],
+			Ticks:  ticks,
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("Failed to encode usage of tenant %s: %v", key.tenant, err)
+			continue
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if _, err := l.file.Write(lines); err != nil {
+		log.Printf("Failed to write usage: %v", err)
+	}
+	clear(l.pending)
+}
+
+func (l *usageLedger) setPrices(prices models.UnitPrices) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prices = prices
+}
+
+// record adds the allocations of one cycle that ran at now.
+func (l *usageLedger) record(now time.Time, tasks []*models.Task) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := now.Truncate(usageInterval).Unix()
+	if l.file != nil && start != l.current {
+		// 区间结束后才写入文件，每个区间每个键一行
+		l.flush()
+		l.current = start
+	}
+	for _, task := range tasks {
+		if task.Allocated == 0 {
+			continue
+		}
+		key := l.add(start, task.Tenant, task.JobID, task.Class, task.Labels, task.Allocated)
+		if l.file != nil {
+			l.pending[key] += task.Allocated
+		}
+	}
+}
+
+// add counts ticks for the interval starting at start and returns their key.
+func (l *usageLedger) add(start int64, tenant, job, class string, labels map[string]string, ticks int) usageKey {
+	key := usageKey{start, tenant, job, class, labelsKey(labels)}
+	if _, exists := l.labels[key.labels]; !exists {
+		l.labels[key.labels] = cloneLabels(labels)
+	}
+	l.ticks[key] += ticks
+	return key
+}
+
+// labelsKey returns the same string for equal label sets.
+func labelsKey(labels map[string]string) string {
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		fmt.Fprintf(&b, "%q=%q,", key, labels[key])
+	}
+	return b.String()
+}
+
+// usageDimensions checks groupBy, defaulting to grouping by tenant.
+func usageDimensions(groupBy []string) ([]string, error) {
+	if len(groupBy) == 0 {
+		return []string{"tenant"}, nil
+	}
+	for _, dimension := range groupBy {
+		switch {
+		case dimension == "tenant", dimension == "job", dimension == "class":
+		case strings.HasPrefix(dimension, "label:") && len(dimension) > len("label:"):
+		default:
+			return nil, fmt.Errorf("%w: cannot group by %q, expected tenant, job, class or label:<key>", ErrInvalidUsageQuery, dimension)
+		}
+	}
+	return groupBy, nil
+}
+
+// report aggregates the usage recorded in the range of query by its dimensions.
+func (l *usageLedger) report(query dto.UsageQuery) (*dto.UsageResponse, error) {
+	dimensions, err := usageDimensions(query.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidUsageQuery)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	response := &dto.UsageResponse{GroupBy: dimensions, Prices: l.prices, Rows: make([]dto.UsageRow, 0)}
+	if !query.From.IsZero() {
+		from := query.From.Truncate(usageInterval)
+		response.From = &from
+	}
+	if !query.To.IsZero() {
+		to := query.To.Truncate(usageInterval)
+		response.To = &to
+	}
+
+	rows := make(map[string]*dto.UsageRow)
+	for key, ticks := range l.ticks {
+		if (response.From != nil && key.start < response.From.Unix()) ||
+			(response.To != nil && key.start >= response.To.Unix()) ||
+			(query.Tenant != "" && key.tenant != query.Tenant) {
+			continue
+		}
+		row := l.usageRow(key, dimensions)
+		id := fmt.Sprintf("%q %q %q %s", row.Tenant, row.Job, row.Class, labelsKey(row.Labels))
+		if existing, exists := rows[id]; exists {
+			row = existing
+		} else {
+			rows[id] = row
+		}
+		cost := float64(ticks) * l.prices.For(key.class)
+		row.BandwidthTicks += ticks
+		row.Cost += cost
+		response.TotalBandwidthTicks += ticks
+		response.TotalCost += cost
+	}
+
+	ids := slices.Sorted(maps.Keys(rows))
+	for _, id := range ids {
+		response.Rows = append(response.Rows, *rows[id])
+	}
+	return response, nil
+}
+
+// usageRow returns an empty row holding the dimensions of key.
+func (l *usageLedger) usageRow(key usageKey, dimensions []string) *dto.UsageRow {
+	row := &dto.UsageRow{}
+	for _, dimension := range dimensions {
+		switch dimension {
+		case "tenant":
+			row.Tenant = key.tenant
+		case "job":
+			row.Job = key.job
+		case "class":
+			row.Class = key.class
+		default:
+			label := strings.TrimPrefix(dimension, "label:")
+			if row.Labels == nil {
+				row.Labels = make(map[string]string)
+			}
+			row.Labels[label] = l.labels[key.labels][label]
+		}
+	}
+	return row
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"scheduler-service/dto"
+	"scheduler-service/models"
+	"scheduler-service/scheduler"
+	"testing"
+	"time"
+)
+
+func TestTaskService_Usage(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	classes, _ := scheduler.ParseServiceClasses("critical=0,standard=0")
+	engine, _ := scheduler.NewEngine(scheduler.EngineOptions{
+		Bandwidth:    4,
+		Classes:      classes,
+		DefaultClass: "standard",
+		Clock:        func() time.Time { return now },
+	})
+	service := NewTaskServiceWithEngine(engine)
+	if err := service.SetUnitPrices(models.UnitPrices{BandwidthTick: 0.5, Classes: map[string]float64{"critical": 2}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 09:00 team-a 运行两个周期，09:05 team-b 运行一个关键任务
+	specs := []dto.TaskSpec{
+		{Duration: 4, MaxParallelism: 2, Labels: map[string]string{"app": "web"}},
+		{Duration: 4, MaxParallelism: 2, Labels: map[string]string{"app": "db"}},
+	}
+	job, _ := service.SubmitTaskSpecs("team-a", specs)
+	service.ExecuteSchedulingCycle()
+	service.ExecuteSchedulingCycle()
+	now = start.Add(5 * time.Minute)
+	service.SubmitTaskSpecs("team-b", []dto.TaskSpec{{Duration: 3, Class: "critical"}})
+	service.ExecuteSchedulingCycle()
+
+	tests := []struct {
+		name     string
+		query    dto.UsageQuery
+		expected []dto.UsageRow
+	}{
+		{"By tenant", dto.UsageQuery{}, []dto.UsageRow{
+			{Tenant: "team-a", BandwidthTicks: 8, Cost: 4},
+			{Tenant: "team-b", BandwidthTicks: 3, Cost: 6},
+		}},
+		{"Time range", dto.UsageQuery{From: start.Add(time.Minute)}, []dto.UsageRow{
+			{Tenant: "team-b", BandwidthTicks: 3, Cost: 6},
+		}},
+		{"By job and label", dto.UsageQuery{Tenant: "team-a", GroupBy: []string{"job", "label:app"}}, []dto.UsageRow{
+			{Job: job.JobID, Labels: map[string]string{"app": "db"}, BandwidthTicks: 4, Cost: 2},
+			{Job: job.JobID, Labels: map[string]string{"app": "web"}, BandwidthTicks: 4, Cost: 2},
+		}},
+		{"By class", dto.UsageQuery{GroupBy: []string{"class"}}, []dto.UsageRow{
+			{Class: "critical", BandwidthTicks: 3, Cost: 6},
+			{Class: "standard", BandwidthTicks: 8, Cost: 4},
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := service.GetUsage(tc.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(usage.Rows) != len(tc.expected) {
+				t.Fatalf("Expected %+v, got %+v", tc.expected, usage.Rows)
+			}
+			for i, row := range usage.Rows {
+				want := tc.expected[i]
+				if row.Tenant != want.Tenant || row.Job != want.Job || row.Class != want.Class ||
+					row.Labels["app"] != want.Labels["app"] || row.BandwidthTicks != want.BandwidthTicks || row.Cost != want.Cost {
+					t.Errorf("Expected %+v, got %+v", want, row)
+				}
+			}
+		})
+	}
+
+	for _, query := range []dto.UsageQuery{
+		{GroupBy: []string{"team"}},
+		{GroupBy: []string{"label:"}},
+		{From: start, To: start},
+	} {
+		if _, err := service.GetUsage(query); !errors.Is(err, ErrInvalidUsageQuery) {
+			t.Errorf("Expected %+v to be invalid, got %v", query, err)
+		}
+	}
+}
+
+func TestTaskService_UsageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	newService := func() *TaskService {
+		engine, _ := scheduler.NewEngine(scheduler.EngineOptions{Bandwidth: 2, Clock: func() time.Time { return now }})
+		service := NewTaskServiceWithEngine(engine)
+		if err := service.OpenUsageFile(path); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return service
+	}
+
+	// 09:00 和 09:01 各运行一个周期，关闭时写入最后一分钟
+	service := newService()
+	service.SubmitTaskSpecs("team-a", []dto.TaskSpec{{Duration: 4, MaxParallelism: 2, Labels: map[string]string{"app": "web"}}})
+	service.ExecuteSchedulingCycle()
+	now = start.Add(time.Minute)
+	service.ExecuteSchedulingCycle()
+	if err := service.CloseUsageFile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 写入一半时崩溃留下的不完整行在重新打开时被丢弃
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	file.WriteString(`{"start": "2024-01-01T09:02:00Z", "tenant": "team-a", "ti`)
+	file.Close()
+
+	service = newService()
+	defer service.CloseUsageFile()
+	tests := []struct {
+		name     string
+		query    dto.UsageQuery
+		expected int
+	}{
+		{"All", dto.UsageQuery{}, 4},
+		{"Second minute", dto.UsageQuery{From: start.Add(time.Minute)}, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			usage, err := service.GetUsage(tc.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if usage.TotalBandwidthTicks != tc.expected {
+				t.Errorf("Expected %d bandwidth-ticks after reopening, got %+v", tc.expected, usage)
+			}
+		})
+	}
+	usage, _ := service.GetUsage(dto.UsageQuery{GroupBy: []string{"label:app"}})
+	if len(usage.Rows) != 1 || usage.Rows[0].Labels["app"] != "web" {
+		t.Errorf("Expected the usage of app web, got %+v", usage.Rows)
+	}
+	if data, _ := os.ReadFile(path); !bytes.HasSuffix(data, []byte("}\n")) {
+		t.Errorf("Expected the incomplete line to be cut off, got %q", data)
+	}
+}
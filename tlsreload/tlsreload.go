@@ -0,0 +1,147 @@
+// Package tlsreload serves TLS with certificate files that are reloaded
+// when they change on disk, so that rotated certificates take effect
+// without a restart.
+package tlsreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthRequire  = "require"  // every client must present a certificate signed by the client CA
+	ClientAuthOptional = "optional" // clients without a certificate are served, others are verified
+)
+
+// checkInterval is how often a handshake looks for changed files.
+const checkInterval = 10 * time.Second
+
+// Options names the files of a TLS server.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, if set, enables mutual TLS: client certificates are
+	// verified against the CA certificates in this PEM file.
+	ClientCAFile string
+	ClientAuth   string // ClientAuthRequire (default) or ClientAuthOptional
+}
+
+// Reloader holds the TLS configuration loaded from the files of its
+// options and reloads it when one of them changes. If a reload fails, for
+// example because the certificate was written before its key, the last
+// good configuration stays in use and the reload is tried again.
+type Reloader struct {
+	options Options
+	now     func() time.Time
+
+	mu       sync.Mutex
+	config   *tls.Config
+	modTimes map[string]time.Time
+	checked  time.Time
+}
+
+func New(options Options) (*Reloader, error) {
+	if options.CertFile == "" || options.KeyFile == "" {
+		return nil, fmt.Errorf("TLS needs both a certificate and a key file")
+	}
+	if options.ClientAuth == "" {
+		options.ClientAuth = ClientAuthRequire
+	}
+	if options.ClientAuth != ClientAuthRequire && options.ClientAuth != ClientAuthOptional {
+		return nil, fmt.Errorf("client auth must be %s or %s, got %s", ClientAuthRequire, ClientAuthOptional, options.ClientAuth)
+	}
+
+	r := &Reloader{options: options, now: time.Now}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the configuration to serve with, such as the TLSConfig
+// of an http.Server started with ListenAndServeTLS("", "").
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// current returns the configuration for a new connection, reloading the
+// files first if they changed since the last check.
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checked) >= checkInterval {
+		r.checked = now
+		if r.changed() {
+			if err := r.reload(); err != nil {
+				log.Printf("Keeping the current TLS certificates, reload failed: %v", err)
+			}
+		}
+	}
+	return r.config
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.options.CertFile, r.options.KeyFile}
+	if r.options.ClientCAFile != "" {
+		files = append(files, r.options.ClientCAFile)
+	}
+	return files
+}
+
+// changed reports whether a file was modified since it was last loaded.
+func (r *Reloader) changed() bool {
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload loads all files and replaces the configuration if they are valid.
+func (r *Reloader) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		return fmt.Errorf("invalid TLS certificate or key: %w", err)
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if r.options.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in client CA file %s", r.options.ClientCAFile)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if r.options.ClientAuth == ClientAuthOptional {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}
@@ -0,0 +1,167 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issue returns a PEM certificate and key for name, signed by parent or
+// self-signed if parent is nil.
+func issue(t *testing.T, name string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, []byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), cert, key
+}
+
+// write writes data to path with the given modification time.
+func write(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to touch %s: %v", path, err)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM, _, _ := issue(t, "server", 1, nil, nil)
+	write(t, filepath.Join(dir, "cert.pem"), certPEM, time.Now())
+	write(t, filepath.Join(dir, "key.pem"), keyPEM, time.Now())
+
+	for _, options := range []Options{
+		{CertFile: filepath.Join(dir, "cert.pem")},
+		{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "missing.pem")},
+		{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "cert.pem")},
+		{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem"), ClientCAFile: filepath.Join(dir, "key.pem")},
+		{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem"), ClientAuth: "sometimes"},
+	} {
+		if _, err := New(options); err == nil {
+			t.Errorf("Expected error for %+v, got nil", options)
+		}
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	start := time.Now().Truncate(time.Second)
+	certPEM, keyPEM, _, _ := issue(t, "server", 1, nil, nil)
+	write(t, options.CertFile, certPEM, start)
+	write(t, options.KeyFile, keyPEM, start)
+
+	reloader, err := New(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := start
+	reloader.now = func() time.Time { return now }
+	serial := func() int64 {
+		cert, _ := x509.ParseCertificate(reloader.current().Certificates[0].Certificate[0])
+		return cert.SerialNumber.Int64()
+	}
+
+	// 轮换证书：先写证书再写密钥，中间的不匹配状态不能生效
+	certPEM, keyPEM, _, _ = issue(t, "server", 2, nil, nil)
+	write(t, options.CertFile, certPEM, start.Add(time.Second))
+	now = now.Add(checkInterval)
+	if got := serial(); got != 1 {
+		t.Errorf("Expected the old certificate while the key is stale, got serial %d", got)
+	}
+	write(t, options.KeyFile, keyPEM, start.Add(2*time.Second))
+	now = now.Add(checkInterval / 2)
+	if got := serial(); got != 1 {
+		t.Errorf("Expected no check within the interval, got serial %d", got)
+	}
+	now = now.Add(checkInterval)
+	if got := serial(); got != 2 {
+		t.Errorf("Expected the rotated certificate, got serial %d", got)
+	}
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caPEM, _, ca, caKey := issue(t, "ca", 1, nil, nil)
+	certPEM, keyPEM, _, _ := issue(t, "server", 2, ca, caKey)
+	clientPEM, clientKeyPEM, _, _ := issue(t, "team-a", 3, ca, caKey)
+	options := Options{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	}
+	write(t, options.CertFile, certPEM, time.Now())
+	write(t, options.KeyFile, keyPEM, time.Now())
+	write(t, options.ClientCAFile, caPEM, time.Now())
+
+	reloader, err := New(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	clientCert, _ := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	client := func(certs []tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+
+	resp, err := client([]tls.Certificate{clientCert}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var body [16]byte
+	n, _ := resp.Body.Read(body[:])
+	if string(body[:n]) != "team-a" {
+		t.Errorf("Expected client team-a, got %q", body[:n])
+	}
+
+	if _, err := client(nil).Get(server.URL); err == nil {
+		t.Errorf("Expected a client without certificate to be refused")
+	}
+}